@@ -1,6 +1,8 @@
 package aggregator
 
 import (
+	"fmt"
+	"math"
 	"sort"
 	"time"
 
@@ -8,28 +10,77 @@ import (
 )
 
 type Config struct {
-	RecencyWeight     float64
-	FrequencyWeight   float64
-	StrengthWeight    float64
-	MinSignalCount    int
-	ConflictThreshold float64
-	RecencyDecayDays  int
+	RecencyWeight         float64 `yaml:"recency_weight"`
+	FrequencyWeight       float64 `yaml:"frequency_weight"`
+	StrengthWeight        float64 `yaml:"strength_weight"`
+	SessionSpreadWeight   float64 `yaml:"session_spread_weight"`
+	FrequencySaturation   float64 `yaml:"frequency_saturation"`
+	SessionSaturation     float64 `yaml:"session_saturation"`
+	SessionRepeatExponent float64 `yaml:"session_repeat_exponent"`
+	MinSignalCount        int     `yaml:"min_signal_count"`
+	ConflictThreshold     float64 `yaml:"conflict_threshold"`
+	RecencyDecayDays      int     `yaml:"recency_decay_days"`
+	GlobalMinProjects     int     `yaml:"global_min_projects"`
+	EvidenceLimit         int     `yaml:"evidence_limit"`
+
+	SupersessionThreshold float64 `yaml:"supersession_threshold"`
+
+	ScopeMinCoverage   float64 `yaml:"scope_min_coverage"`
+	ScopeMaxExtensions int     `yaml:"scope_max_extensions"`
+
+	RecencyModel            string       `yaml:"recency_model"`
+	RecencyFloor            float64      `yaml:"recency_floor"`
+	RecencyHalfLifeDays     float64      `yaml:"recency_half_life_days"`
+	RecencyHalfLifeSessions float64      `yaml:"recency_half_life_sessions"`
+	RecencySteps            []StepWindow `yaml:"recency_steps"`
 }
 
 func DefaultConfig() Config {
 	return Config{
-		RecencyWeight:     0.4,
-		FrequencyWeight:   0.3,
-		StrengthWeight:    0.3,
-		MinSignalCount:    2,
-		ConflictThreshold: 0.3,
-		RecencyDecayDays:  30,
+		RecencyWeight:         0.4,
+		FrequencyWeight:       0.3,
+		StrengthWeight:        0.3,
+		SessionSpreadWeight:   0.3,
+		FrequencySaturation:   5,
+		SessionSaturation:     3,
+		SessionRepeatExponent: 0.5,
+		MinSignalCount:        2,
+		ConflictThreshold:     0.3,
+		RecencyDecayDays:      30,
+		GlobalMinProjects:     3,
+		EvidenceLimit:         5,
+
+		SupersessionThreshold: 0.8,
+
+		ScopeMinCoverage:   0.8,
+		ScopeMaxExtensions: 3,
+
+		RecencyModel:            RecencyLinear,
+		RecencyFloor:            0.1,
+		RecencyHalfLifeDays:     14,
+		RecencyHalfLifeSessions: 10,
+		RecencySteps:            DefaultStepWindows(),
 	}
 }
 
+func (c Config) Validate() error {
+	if _, err := NewRecencyModel(c, time.Now(), nil); err != nil {
+		return err
+	}
+	if c.RecencyFloor < 0 || c.RecencyFloor > 1 {
+		return fmt.Errorf("recency_floor must be between 0 and 1, got %v", c.RecencyFloor)
+	}
+	if c.RecencyWeight+c.FrequencyWeight+c.StrengthWeight+c.SessionSpreadWeight <= 0 {
+		return fmt.Errorf("at least one confidence weight must be positive")
+	}
+	return nil
+}
+
 type Aggregator struct {
-	config Config
-	now    time.Time
+	config     Config
+	now        time.Time
+	recency    RecencyModel
+	extensions map[string]struct{}
 }
 
 func NewAggregator(cfg Config) *Aggregator {
@@ -52,10 +103,21 @@ func (a *Aggregator) Aggregate(sigs []signals.Signal) *signals.FlavorProfile {
 		}
 	}
 
+	recency, err := NewRecencyModel(a.config, a.now, sigs)
+	if err != nil {
+		recency = &LinearDecay{Now: a.now, DecayDays: a.config.RecencyDecayDays, Floor: a.config.RecencyFloor}
+	}
+	a.recency = recency
+	a.extensions = extensionsOf(sigs)
+
 	grouped := a.groupSignals(sigs)
 
 	for key, groupedSigs := range grouped {
-		pref, conflict := a.aggregateGroup(key, groupedSigs)
+		pref, conflict, superseded := a.aggregateGroup(key, groupedSigs)
+
+		if superseded != nil {
+			profile.Superseded = append(profile.Superseded, *superseded)
+		}
 
 		if conflict != nil {
 			profile.Conflicts = append(profile.Conflicts, *conflict)
@@ -73,6 +135,9 @@ func (a *Aggregator) groupSignals(sigs []signals.Signal) map[string][]signals.Si
 	groups := make(map[string][]signals.Signal)
 
 	for _, sig := range sigs {
+		if sig.Type == signals.SignalUsage {
+			continue
+		}
 		key := string(sig.Type) + "::" + sig.Category + "::" + sig.Key
 		groups[key] = append(groups[key], sig)
 	}
@@ -80,21 +145,38 @@ func (a *Aggregator) groupSignals(sigs []signals.Signal) map[string][]signals.Si
 	return groups
 }
 
-func (a *Aggregator) aggregateGroup(_ string, sigs []signals.Signal) (*signals.Preference, *signals.ConflictingPreference) {
+func (a *Aggregator) aggregateGroup(_ string, sigs []signals.Signal) (*signals.Preference, *signals.ConflictingPreference, *signals.SupersededPreference) {
 	if len(sigs) < a.config.MinSignalCount {
 		if len(sigs) > 0 && sigs[0].Strength == signals.StrengthExplicit {
-			return a.buildPreference(sigs), nil
+			return a.buildPreference(sigs), nil, nil
 		}
-		return nil, nil
+		return nil, nil, nil
+	}
+
+	if !hasAlternatives(sigs[0]) {
+		return a.buildPreference(sigs), nil, nil
 	}
 
 	valueGroups := a.groupByValue(sigs)
 
 	if len(valueGroups) > 1 && a.hasConflict(valueGroups) {
-		return nil, a.buildConflict(sigs[0].Category, sigs[0].Key, valueGroups)
+		if superseded, current := a.detectSupersession(sigs); superseded != nil {
+			return a.buildPreference(current), nil, superseded
+		}
+		return nil, a.buildConflict(sigs[0].Category, sigs[0].Key, valueGroups), nil
 	}
 
-	return a.buildPreference(sigs), nil
+	return a.buildPreference(sigs), nil, nil
+}
+
+// hasAlternatives reports whether the values of a signal's group are options
+// for one setting, such as camelCase or snake_case naming or npm or pnpm as
+// package manager, that can conflict with or replace one another. Corrections
+// and approvals carry the user's message and other stack signals the file or
+// command they were seen in, so their values only restate the key and are
+// never weighed against each other.
+func hasAlternatives(sig signals.Signal) bool {
+	return sig.Type == signals.SignalStyle || sig.Type == signals.SignalStack && sig.Category == "choice"
 }
 
 func (a *Aggregator) groupByValue(sigs []signals.Signal) map[string][]signals.Signal {
@@ -107,6 +189,9 @@ func (a *Aggregator) groupByValue(sigs []signals.Signal) map[string][]signals.Si
 	return groups
 }
 
+// hasConflict compares the confidence of the two strongest values, so that a
+// value repeated many times in one session does not drown out one held across
+// many sessions.
 func (a *Aggregator) hasConflict(valueGroups map[string][]signals.Signal) bool {
 	if len(valueGroups) <= 1 {
 		return false
@@ -114,7 +199,7 @@ func (a *Aggregator) hasConflict(valueGroups map[string][]signals.Signal) bool {
 
 	var scores []float64
 	for _, sigs := range valueGroups {
-		score := a.calculateGroupScore(sigs)
+		score, _ := a.confidence(sigs)
 		scores = append(scores, score)
 	}
 
@@ -133,33 +218,201 @@ func (a *Aggregator) hasConflict(valueGroups map[string][]signals.Signal) bool {
 	return secondScore/topScore >= a.config.ConflictThreshold
 }
 
-func (a *Aggregator) calculateGroupScore(sigs []signals.Signal) float64 {
-	var totalScore float64
+func (a *Aggregator) detectSupersession(sigs []signals.Signal) (*signals.SupersededPreference, []signals.Signal) {
+	ordered := make([]signals.Signal, len(sigs))
+	copy(ordered, sigs)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Timestamp.Before(ordered[j].Timestamp)
+	})
+
+	minCount := a.config.MinSignalCount
+	if minCount < 1 {
+		minCount = 1
+	}
+
+	bestSplit := -1
+	bestScore := 0.0
+	var bestOld, bestNew string
+
+	for split := minCount; split <= len(ordered)-minCount; split++ {
+		before, after := ordered[:split], ordered[split:]
+
+		oldValue, oldCount, beforePurity := a.dominantValue(before)
+		newValue, newCount, afterPurity := a.dominantValue(after)
+		if oldValue == newValue || oldCount < minCount || newCount < minCount {
+			continue
+		}
+
+		if beforePurity < a.config.SupersessionThreshold || afterPurity < a.config.SupersessionThreshold {
+			continue
+		}
+
+		if score := beforePurity + afterPurity; score > bestScore {
+			bestScore = score
+			bestSplit = split
+			bestOld, bestNew = oldValue, newValue
+		}
+	}
+
+	if bestSplit < 0 {
+		return nil, nil
+	}
+
+	superseded := &signals.SupersededPreference{
+		Category: ordered[0].Category,
+		Key:      ordered[0].Key,
+		OldValue: bestOld,
+		NewValue: bestNew,
+		Projects: projectsOf(ordered),
+	}
+
+	var current []signals.Signal
+	for i, sig := range ordered {
+		switch {
+		case i < bestSplit && sig.Value == bestOld:
+			superseded.OldCount++
+			superseded.OldLastSeen = sig.Timestamp
+		case i >= bestSplit && sig.Value == bestNew:
+			if superseded.NewCount == 0 {
+				superseded.SwitchedAt = sig.Timestamp
+			}
+			superseded.NewCount++
+			superseded.NewLastSeen = sig.Timestamp
+			current = append(current, sig)
+		}
+	}
+
+	return superseded, current
+}
+
+func (a *Aggregator) collectEvidence(sigs []signals.Signal) []signals.Evidence {
+	var evidence []signals.Evidence
 
 	for _, sig := range sigs {
-		recency := a.recencyScore(sig.Timestamp)
-		strength := float64(sig.Strength) / 4.0
+		if len(evidence) >= a.config.EvidenceLimit {
+			break
+		}
+		evidence = append(evidence, signals.Evidence{
+			Text:      sig.Value,
+			Context:   sig.Context,
+			Timestamp: sig.Timestamp,
+			SessionID: sig.SessionID,
+			Project:   sig.Project,
+
+			MessageUUID: sig.MessageUUID,
+			SourceFile:  sig.SourceFile,
+			SourceLine:  sig.SourceLine,
+		})
+	}
 
-		score := (a.config.RecencyWeight * recency) +
-			(a.config.StrengthWeight * strength)
+	return evidence
+}
+
+func projectsOf(sigs []signals.Signal) []string {
+	seen := make(map[string]struct{})
+	var projects []string
 
-		totalScore += score
+	for _, sig := range sigs {
+		if sig.Project == "" {
+			continue
+		}
+		if _, ok := seen[sig.Project]; ok {
+			continue
+		}
+		seen[sig.Project] = struct{}{}
+		projects = append(projects, sig.Project)
 	}
 
-	frequency := float64(len(sigs))
-	totalScore += a.config.FrequencyWeight * frequency
+	sort.Strings(projects)
+	return projects
+}
+
+// dominantValue returns the value with the highest confidence among sigs,
+// how many signals carry it, and its share of all signals, with repeats
+// within a session discounted the way confidence discounts them.
+func (a *Aggregator) dominantValue(sigs []signals.Signal) (string, int, float64) {
+	byValue := a.groupByValue(sigs)
+	values := make([]string, 0, len(byValue))
+	for value := range byValue {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+
+	var best string
+	var bestScore, bestFrequency, total float64
+	for _, value := range values {
+		score, _ := a.confidence(byValue[value])
+		frequency := a.effectiveFrequency(sessionActivity(byValue[value]))
+		total += frequency
+		if best == "" || score > bestScore {
+			best, bestScore, bestFrequency = value, score, frequency
+		}
+	}
 
-	return totalScore
+	if total == 0 {
+		return best, len(byValue[best]), 0
+	}
+	return best, len(byValue[best]), bestFrequency / total
 }
 
-func (a *Aggregator) recencyScore(timestamp time.Time) float64 {
-	daysSince := a.now.Sub(timestamp).Hours() / 24
+// confidence returns a 0..1 score that is comparable across categories,
+// together with the components it was weighted from.
+func (a *Aggregator) confidence(sigs []signals.Signal) (float64, signals.ConfidenceBreakdown) {
+	var breakdown signals.ConfidenceBreakdown
+	if len(sigs) == 0 {
+		return 0, breakdown
+	}
 
-	if daysSince >= float64(a.config.RecencyDecayDays) {
-		return 0.1
+	for _, sig := range sigs {
+		breakdown.Recency += a.recency.Score(sig)
+		breakdown.Strength += float64(sig.Strength) / float64(signals.StrengthExplicit)
 	}
 
-	return 1.0 - (daysSince / float64(a.config.RecencyDecayDays) * 0.9)
+	n := float64(len(sigs))
+	breakdown.Recency /= n
+	breakdown.Strength /= n
+
+	sessions := sessionActivity(sigs)
+	breakdown.Frequency = saturate(a.effectiveFrequency(sessions), a.config.FrequencySaturation)
+	breakdown.SessionSpread = saturate(float64(len(sessions)), a.config.SessionSaturation)
+
+	totalWeight := a.config.RecencyWeight + a.config.FrequencyWeight +
+		a.config.StrengthWeight + a.config.SessionSpreadWeight
+	if totalWeight <= 0 {
+		return 0, breakdown
+	}
+
+	score := (a.config.RecencyWeight*breakdown.Recency +
+		a.config.FrequencyWeight*breakdown.Frequency +
+		a.config.StrengthWeight*breakdown.Strength +
+		a.config.SessionSpreadWeight*breakdown.SessionSpread) / totalWeight
+
+	return score, breakdown
+}
+
+func sessionActivity(sigs []signals.Signal) map[string]int {
+	sessions := make(map[string]int)
+	for _, sig := range sigs {
+		sessions[sig.SessionID]++
+	}
+	return sessions
+}
+
+// effectiveFrequency discounts repeats within one session so that a rule
+// restated across many sessions outweighs the same rule repeated in one.
+func (a *Aggregator) effectiveFrequency(sessions map[string]int) float64 {
+	var total float64
+	for _, count := range sessions {
+		total += math.Pow(float64(count), a.config.SessionRepeatExponent)
+	}
+	return total
+}
+
+func saturate(x, scale float64) float64 {
+	if scale <= 0 {
+		return 1.0
+	}
+	return 1.0 - math.Exp(-x/scale)
 }
 
 func (a *Aggregator) buildPreference(sigs []signals.Signal) *signals.Preference {
@@ -169,15 +422,24 @@ func (a *Aggregator) buildPreference(sigs []signals.Signal) *signals.Preference
 
 	mostRecent := sigs[0]
 	oldest := sigs[len(sigs)-1]
+	confidence, breakdown := a.confidence(sigs)
 
 	return &signals.Preference{
 		Category:    mostRecent.Category,
 		Key:         mostRecent.Key,
 		Value:       mostRecent.Value,
-		Confidence:  a.calculateGroupScore(sigs),
+		Confidence:  confidence,
+		Breakdown:   breakdown,
 		SignalCount: len(sigs),
 		FirstSeen:   oldest.Timestamp,
 		LastSeen:    mostRecent.Timestamp,
+
+		SessionCount: len(sessionActivity(sigs)),
+		SessionSpan:  mostRecent.Timestamp.Sub(oldest.Timestamp),
+
+		Projects: projectsOf(sigs),
+		Evidence: a.collectEvidence(sigs),
+		Scope:    a.inferScope(sigs),
 	}
 }
 
@@ -187,26 +449,30 @@ func (a *Aggregator) buildConflict(category, key string, valueGroups map[string]
 		Key:      key,
 	}
 
+	var all []signals.Signal
 	for value, sigs := range valueGroups {
 		if len(sigs) == 0 {
 			continue
 		}
+		all = append(all, sigs...)
 
 		sort.Slice(sigs, func(i, j int) bool {
 			return sigs[i].Timestamp.After(sigs[j].Timestamp)
 		})
 
+		confidence, _ := a.confidence(sigs)
 		conflict.Values = append(conflict.Values, signals.ConflictValue{
 			Value:       value,
 			Timestamp:   sigs[0].Timestamp,
 			SignalCount: len(sigs),
-			Strength:    a.calculateGroupScore(sigs),
+			Strength:    confidence,
 		})
 	}
 
 	sort.Slice(conflict.Values, func(i, j int) bool {
 		return conflict.Values[i].Timestamp.After(conflict.Values[j].Timestamp)
 	})
+	conflict.Projects = projectsOf(all)
 
 	return conflict
 }
@@ -240,4 +506,8 @@ func (a *Aggregator) sortByConfidence(profile *signals.FlavorProfile) {
 	sort.Slice(profile.Approvals, func(i, j int) bool {
 		return profile.Approvals[i].Confidence > profile.Approvals[j].Confidence
 	})
+
+	sort.Slice(profile.Superseded, func(i, j int) bool {
+		return profile.Superseded[i].SwitchedAt.After(profile.Superseded[j].SwitchedAt)
+	})
 }
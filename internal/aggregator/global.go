@@ -0,0 +1,80 @@
+package aggregator
+
+import (
+	"github.com/strrl/auto-flavor/internal/signals"
+)
+
+// SplitByProjects separates a profile aggregated across many projects into a
+// user-level profile, holding everything seen in at least minProjects
+// projects, and one profile per project for the rules that stayed local.
+// User-level rules keep only file type scopes, since a directory means
+// nothing outside the project it was found in.
+func SplitByProjects(profile *signals.FlavorProfile, minProjects int) (*signals.FlavorProfile, map[string]*signals.FlavorProfile) {
+	user := emptyProfileLike(profile)
+	perProject := make(map[string]*signals.FlavorProfile)
+
+	projectProfile := func(project string) *signals.FlavorProfile {
+		if _, ok := perProject[project]; !ok {
+			perProject[project] = emptyProfileLike(profile)
+		}
+		return perProject[project]
+	}
+
+	splitPreferences := func(prefs []signals.Preference, field func(*signals.FlavorProfile) *[]signals.Preference) {
+		for _, pref := range prefs {
+			if len(pref.Projects) >= minProjects {
+				pref.Scope = withoutDir(pref.Scope)
+				*field(user) = append(*field(user), pref)
+				continue
+			}
+			for _, project := range pref.Projects {
+				target := field(projectProfile(project))
+				*target = append(*target, pref)
+			}
+		}
+	}
+
+	splitPreferences(profile.StackPreferences, func(p *signals.FlavorProfile) *[]signals.Preference { return &p.StackPreferences })
+	splitPreferences(profile.StylePreferences, func(p *signals.FlavorProfile) *[]signals.Preference { return &p.StylePreferences })
+	splitPreferences(profile.Corrections, func(p *signals.FlavorProfile) *[]signals.Preference { return &p.Corrections })
+	splitPreferences(profile.Approvals, func(p *signals.FlavorProfile) *[]signals.Preference { return &p.Approvals })
+
+	for _, conflict := range profile.Conflicts {
+		if len(conflict.Projects) >= minProjects {
+			user.Conflicts = append(user.Conflicts, conflict)
+			continue
+		}
+		for _, project := range conflict.Projects {
+			target := projectProfile(project)
+			target.Conflicts = append(target.Conflicts, conflict)
+		}
+	}
+
+	for _, superseded := range profile.Superseded {
+		if len(superseded.Projects) >= minProjects {
+			user.Superseded = append(user.Superseded, superseded)
+			continue
+		}
+		for _, project := range superseded.Projects {
+			target := projectProfile(project)
+			target.Superseded = append(target.Superseded, superseded)
+		}
+	}
+
+	return user, perProject
+}
+
+func withoutDir(scope *signals.Scope) *signals.Scope {
+	if scope == nil || len(scope.Extensions) == 0 {
+		return nil
+	}
+	return &signals.Scope{Extensions: scope.Extensions}
+}
+
+func emptyProfileLike(profile *signals.FlavorProfile) *signals.FlavorProfile {
+	return &signals.FlavorProfile{
+		CreatedAt:        profile.CreatedAt,
+		AnalyzedMessages: profile.AnalyzedMessages,
+		TimeRange:        profile.TimeRange,
+	}
+}
@@ -0,0 +1,161 @@
+package aggregator
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/strrl/auto-flavor/internal/signals"
+)
+
+const (
+	RecencyLinear      = "linear"
+	RecencyExponential = "exponential"
+	RecencyStep        = "step"
+	RecencySession     = "session"
+)
+
+// RecencyModel scores how recent a signal is, from 1.0 for a fresh signal down
+// to a model-specific floor for old ones.
+type RecencyModel interface {
+	Score(sig signals.Signal) float64
+}
+
+type StepWindow struct {
+	Days  int     `yaml:"days"`
+	Score float64 `yaml:"score"`
+}
+
+func DefaultStepWindows() []StepWindow {
+	return []StepWindow{
+		{Days: 7, Score: 1.0},
+		{Days: 30, Score: 0.6},
+		{Days: 90, Score: 0.3},
+	}
+}
+
+func NewRecencyModel(cfg Config, now time.Time, sigs []signals.Signal) (RecencyModel, error) {
+	switch cfg.RecencyModel {
+	case "", RecencyLinear:
+		return &LinearDecay{Now: now, DecayDays: cfg.RecencyDecayDays, Floor: cfg.RecencyFloor}, nil
+	case RecencyExponential:
+		return &ExponentialDecay{Now: now, HalfLifeDays: cfg.RecencyHalfLifeDays, Floor: cfg.RecencyFloor}, nil
+	case RecencyStep:
+		windows := make([]StepWindow, len(cfg.RecencySteps))
+		copy(windows, cfg.RecencySteps)
+		sort.Slice(windows, func(i, j int) bool {
+			return windows[i].Days < windows[j].Days
+		})
+		return &StepDecay{Now: now, Windows: windows, Floor: cfg.RecencyFloor}, nil
+	case RecencySession:
+		return NewSessionDecay(sigs, cfg.RecencyHalfLifeSessions, cfg.RecencyFloor), nil
+	default:
+		return nil, fmt.Errorf("unknown recency model %q", cfg.RecencyModel)
+	}
+}
+
+type LinearDecay struct {
+	Now       time.Time
+	DecayDays int
+	Floor     float64
+}
+
+func (m *LinearDecay) Score(sig signals.Signal) float64 {
+	if m.DecayDays <= 0 {
+		return 1.0
+	}
+
+	daysSince := daysBetween(sig.Timestamp, m.Now)
+	if daysSince >= float64(m.DecayDays) {
+		return m.Floor
+	}
+
+	return 1.0 - (daysSince / float64(m.DecayDays) * (1.0 - m.Floor))
+}
+
+type ExponentialDecay struct {
+	Now          time.Time
+	HalfLifeDays float64
+	Floor        float64
+}
+
+func (m *ExponentialDecay) Score(sig signals.Signal) float64 {
+	if m.HalfLifeDays <= 0 {
+		return 1.0
+	}
+
+	score := math.Pow(0.5, daysBetween(sig.Timestamp, m.Now)/m.HalfLifeDays)
+	return math.Max(score, m.Floor)
+}
+
+type StepDecay struct {
+	Now     time.Time
+	Windows []StepWindow
+	Floor   float64
+}
+
+func (m *StepDecay) Score(sig signals.Signal) float64 {
+	daysSince := daysBetween(sig.Timestamp, m.Now)
+
+	for _, w := range m.Windows {
+		if daysSince <= float64(w.Days) {
+			return w.Score
+		}
+	}
+
+	return m.Floor
+}
+
+// SessionDecay ages signals by how many sessions have happened since, rather
+// than by wall-clock time, so a break from the project does not erase history.
+type SessionDecay struct {
+	HalfLifeSessions float64
+	Floor            float64
+	sessionsSince    map[string]int
+}
+
+func NewSessionDecay(sigs []signals.Signal, halfLifeSessions, floor float64) *SessionDecay {
+	lastSeen := make(map[string]time.Time)
+	for _, sig := range sigs {
+		if sig.Timestamp.After(lastSeen[sig.SessionID]) {
+			lastSeen[sig.SessionID] = sig.Timestamp
+		}
+	}
+
+	sessions := make([]string, 0, len(lastSeen))
+	for id := range lastSeen {
+		sessions = append(sessions, id)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return lastSeen[sessions[i]].After(lastSeen[sessions[j]])
+	})
+
+	sessionsSince := make(map[string]int, len(sessions))
+	for i, id := range sessions {
+		sessionsSince[id] = i
+	}
+
+	return &SessionDecay{
+		HalfLifeSessions: halfLifeSessions,
+		Floor:            floor,
+		sessionsSince:    sessionsSince,
+	}
+}
+
+func (m *SessionDecay) Score(sig signals.Signal) float64 {
+	if m.HalfLifeSessions <= 0 {
+		return 1.0
+	}
+
+	score := math.Pow(0.5, float64(m.sessionsSince[sig.SessionID])/m.HalfLifeSessions)
+	return math.Max(score, m.Floor)
+}
+
+func daysBetween(from, to time.Time) float64 {
+	days := to.Sub(from).Hours() / 24
+	if days < 0 {
+		return 0
+	}
+	return days
+}
@@ -0,0 +1,86 @@
+package aggregator
+
+import (
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/strrl/auto-flavor/internal/signals"
+)
+
+// inferScope narrows a preference to where it was expressed: the deepest
+// directory holding every file edited around its signals, and their file
+// extensions when those are only some of the project's. Stack preferences
+// describe the project as a whole and are never scoped, and neither are
+// preferences whose signals mostly came without edited files. Directories
+// are relative to each signal's project, so they are only compared when all
+// signals come from the same one.
+func (a *Aggregator) inferScope(sigs []signals.Signal) *signals.Scope {
+	if len(sigs) == 0 || sigs[0].Type == signals.SignalStack {
+		return nil
+	}
+
+	var files []string
+	withFiles := 0
+	for _, sig := range sigs {
+		if len(sig.Files) > 0 {
+			withFiles++
+			files = append(files, sig.Files...)
+		}
+	}
+	if withFiles == 0 || float64(withFiles) < a.config.ScopeMinCoverage*float64(len(sigs)) {
+		return nil
+	}
+
+	scope := &signals.Scope{}
+	if dir := commonDir(files); dir != "." && len(projectsOf(sigs)) <= 1 {
+		scope.Dir = dir
+	}
+
+	exts := make(map[string]struct{})
+	for _, file := range files {
+		exts[path.Ext(file)] = struct{}{}
+	}
+	if _, ok := exts[""]; !ok && len(exts) <= a.config.ScopeMaxExtensions && len(exts) < len(a.extensions) {
+		for ext := range exts {
+			scope.Extensions = append(scope.Extensions, ext)
+		}
+		sort.Strings(scope.Extensions)
+	}
+
+	if scope.Dir == "" && len(scope.Extensions) == 0 {
+		return nil
+	}
+	return scope
+}
+
+func commonDir(files []string) string {
+	if len(files) == 0 {
+		return "."
+	}
+
+	dir := path.Dir(files[0])
+	for _, file := range files[1:] {
+		for dir != "." && !strings.HasPrefix(file, dir+"/") {
+			dir = path.Dir(dir)
+		}
+	}
+	return dir
+}
+
+// extensionsOf collects the file extensions edited anywhere in the analyzed
+// history, against which a preference's extensions count as a narrower scope.
+// Language signals carry the edited file as their value.
+func extensionsOf(sigs []signals.Signal) map[string]struct{} {
+	exts := make(map[string]struct{})
+	for _, sig := range sigs {
+		if sig.Type == signals.SignalStack && sig.Category == "language" {
+			exts[filepath.Ext(sig.Value)] = struct{}{}
+		}
+		for _, file := range sig.Files {
+			exts[path.Ext(file)] = struct{}{}
+		}
+	}
+	return exts
+}
@@ -3,21 +3,44 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/strrl/auto-flavor/internal/aggregator"
+	"github.com/strrl/auto-flavor/internal/config"
 	"github.com/strrl/auto-flavor/internal/output"
 	"github.com/strrl/auto-flavor/internal/parser"
+	"github.com/strrl/auto-flavor/internal/profile"
 	"github.com/strrl/auto-flavor/internal/signals"
+	"github.com/strrl/auto-flavor/internal/stats"
 )
 
 var (
-	analyzePath  string
-	analyzeDays  int
-	analyzeAll   bool
-	analyzeApply bool
+	analyzePath   string
+	analyzeDays   int
+	analyzeAll    bool
+	analyzeApply  bool
+	analyzeLocal  bool
+	analyzeTarget []string
+	analyzeSet    []string
+	analyzeGlobal bool
+	analyzeExport string
+	analyzeHTML   string
+	analyzeAuthor string
+
+	analyzeAnonymize bool
+	analyzeRedaction string
+	analyzeCoarsen   string
+
+	analyzeSubdirs     bool
+	analyzeMatchGit    bool
+	analyzeMatchRemote bool
+	analyzeMatchMoved  bool
 )
 
 var analyzeCmd = &cobra.Command{
@@ -25,7 +48,16 @@ var analyzeCmd = &cobra.Command{
 	Short: "Analyze Claude Code chat history to extract coding flavor",
 	Long: `Analyze Claude Code chat history for a project to extract the developer's
 coding preferences, style patterns, and corrections. Generates one flavor file
-per rule in .flavor/<rule>.md and optionally appends rules to CLAUDE.md.`,
+per rule in .flavor/<rule>.md, or with --layout single a consolidated
+.flavor/FLAVOR.md, and optionally writes rules to CLAUDE.md or to other
+assistants' instruction files selected with --target.
+
+By default CLAUDE.md gets the rules inline, or an @import of FLAVOR.md when
+that is written; --apply-strategy chooses explicitly. The import is added only
+once and not at all when CLAUDE.md, a CLAUDE.md of a parent directory up to
+the project, or a CLAUDE.local.md next to them already imports FLAVOR.md.
+--claude-file selects a nested CLAUDE.md and --local the personal
+CLAUDE.local.md, which is not meant to be committed.`,
 	RunE: runAnalyze,
 }
 
@@ -35,55 +67,303 @@ func init() {
 	analyzeCmd.Flags().StringVarP(&analyzePath, "path", "p", "", "Path to the project to analyze (default: current directory)")
 	analyzeCmd.Flags().IntVarP(&analyzeDays, "days", "d", 30, "Number of days to analyze")
 	analyzeCmd.Flags().BoolVar(&analyzeAll, "all", false, "Analyze all sessions regardless of time")
-	analyzeCmd.Flags().BoolVar(&analyzeApply, "apply", false, "Also append to target project's CLAUDE.md")
+	analyzeCmd.Flags().BoolVar(&analyzeApply, "apply", false, "Also write rules to the project's CLAUDE.md (same as --target claude)")
+	analyzeCmd.Flags().BoolVar(&analyzeLocal, "local", false, "Write rules to the personal CLAUDE.local.md instead of CLAUDE.md (implies --apply)")
+	analyzeCmd.Flags().StringSliceVar(&analyzeTarget, "target", nil, "Write rules to these assistants' instruction files: "+strings.Join(output.TargetNames(), ", "))
+	analyzeCmd.Flags().BoolVar(&analyzeGlobal, "global", false, "Analyze all projects and extract user-level flavor into ~/.claude")
+	analyzeCmd.Flags().StringVar(&analyzeExport, "export", "", "Write the profile as JSON to this file for sharing or merging")
+	analyzeCmd.Flags().StringVar(&analyzeHTML, "html", "", "Also write a self-contained HTML report to this file")
+	analyzeCmd.Flags().StringVar(&analyzeAuthor, "author", "", "Author name recorded in the exported profile (default: git user.name)")
+	analyzeCmd.Flags().BoolVar(&analyzeAnonymize, "anonymize", false, "Strip identifying details from the exported profile")
+	analyzeCmd.Flags().StringVar(&analyzeRedaction, "evidence", profile.EvidenceHash, "How --anonymize treats evidence and message text: hash or redact")
+	analyzeCmd.Flags().StringVar(&analyzeCoarsen, "coarsen", profile.CoarsenDay, "Timestamp granularity for --anonymize: day, week or month")
+	analyzeCmd.Flags().BoolVar(&analyzeSubdirs, "subdirs", true, "Include sessions started in subdirectories of the project")
+	analyzeCmd.Flags().BoolVar(&analyzeMatchGit, "match-git", true, "Match sessions by git root, including other worktrees of the repository")
+	analyzeCmd.Flags().BoolVar(&analyzeMatchRemote, "match-remote", true, "Match sessions in clones with the same remote URL")
+	analyzeCmd.Flags().BoolVar(&analyzeMatchMoved, "match-moved", false, "Match sessions in deleted directories named like the repository that worked on one of its branches")
+	analyzeCmd.Flags().StringArrayVar(&analyzeSet, "set", nil, "Override a config setting (key=value), may be repeated")
+	analyzeCmd.Flags().Int("min-signals", 0, "Minimum signals before a preference is reported")
+	analyzeCmd.Flags().Float64("conflict-threshold", 0, "Second-best/best score ratio that marks a conflict")
+	analyzeCmd.Flags().Int("recency-decay-days", 0, "Days after which signals reach minimum recency weight")
+	analyzeCmd.Flags().String("recency-model", "", "Recency decay model: linear, exponential, step or session")
+	analyzeCmd.Flags().String("output-dir", "", "Flavor output directory relative to the project")
+	analyzeCmd.Flags().String("layout", "", "Flavor output layout: files (one per rule), single (FLAVOR.md) or both")
+	analyzeCmd.Flags().String("apply-strategy", "", "How rules reach CLAUDE.md: inline, import (of FLAVOR.md) or auto")
+	analyzeCmd.Flags().String("claude-file", "", "CLAUDE.md to apply rules to, relative to the project, e.g. services/api/CLAUDE.md")
 }
 
 func runAnalyze(cmd *cobra.Command, args []string) error {
+	if analyzeGlobal {
+		if analyzeLocal {
+			return fmt.Errorf("--local applies to project analysis only")
+		}
+		for _, target := range analyzeTarget {
+			if target != "claude" {
+				return fmt.Errorf("--target %s applies to project analysis only, ~/.claude is read by Claude Code alone", target)
+			}
+		}
+		return runGlobalAnalyze(cmd)
+	}
+
 	projectPath, err := resolveProjectPath(analyzePath)
 	if err != nil {
 		return err
 	}
 
+	cfg, _, err := loadConfig(cmd, projectPath, analyzeSet)
+	if err != nil {
+		return err
+	}
+	if analyzeLocal {
+		cfg.Output.ClaudeFile = output.LocalClaudeFile(cfg.Output.ClaudeFile)
+	}
+
 	fmt.Printf("Analyzing project: %s\n", projectPath)
-	fmt.Printf("Output directory: %s/.flavor/\n", projectPath)
+	fmt.Printf("Output directory: %s/%s/\n", projectPath, cfg.Output.Dir)
 
 	p, err := parser.NewParser()
 	if err != nil {
 		return fmt.Errorf("failed to create parser: %w", err)
 	}
 
-	count, first, last, err := p.GetProjectStats(projectPath)
+	matchOpts := parser.MatchOptions{
+		IncludeSubdirs: analyzeSubdirs,
+		MatchGitRoot:   analyzeMatchGit,
+		MatchRemote:    analyzeMatchRemote,
+		MatchMoved:     analyzeMatchMoved,
+	}
+
+	paths, err := p.ResolveProjectPaths(projectPath, matchOpts)
+	if err != nil {
+		return fmt.Errorf("failed to resolve project paths: %w", err)
+	}
+
+	if len(paths) > 1 {
+		fmt.Printf("Matched %d recorded directories:\n", len(paths))
+		for _, path := range paths {
+			fmt.Printf("  - %s\n", path)
+		}
+	}
+
+	count, first, last, err := p.GetPathsStats(paths)
 	if err != nil {
 		return fmt.Errorf("failed to get project stats: %w", err)
 	}
 
-	if count == 0 {
+	if count > 0 {
+		fmt.Printf("Found %d Claude Code messages from %s to %s\n", count, first.Format("2006-01-02"), last.Format("2006-01-02"))
+	}
+
+	since := analysisSince()
+
+	entries, err := fetchFromSources(p, cfg, parser.SourceFilter{Project: projectPath, Match: matchOpts, Since: since})
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
 		return fmt.Errorf("no chat history found for project: %s", projectPath)
 	}
 
-	fmt.Printf("Found %d messages from %s to %s\n", count, first.Format("2006-01-02"), last.Format("2006-01-02"))
+	profile, sigs := buildProfile(cfg, entries, func(string) string { return projectPath })
 
-	var since time.Time
-	if !analyzeAll {
-		since = time.Now().AddDate(0, 0, -analyzeDays)
-		fmt.Printf("Analyzing last %d days (since %s)\n", analyzeDays, since.Format("2006-01-02"))
-	} else {
-		fmt.Println("Analyzing all history")
+	if analyzeExport != "" {
+		if err := exportProfile(profile, projectPath); err != nil {
+			return err
+		}
+	}
+
+	if analyzeHTML != "" {
+		usage := stats.Compute(entries, sigs, stats.Options{
+			TopN:      10,
+			ProjectOf: func(string) string { return projectPath },
+		})
+		if err := writeHTMLReport(cfg, filepath.Base(projectPath), profile, sigs, usage); err != nil {
+			return err
+		}
+	}
+
+	return writeFlavor(cfg, profile, projectPath, applyTargets(analyzeApply || analyzeLocal, analyzeTarget))
+}
+
+func exportProfile(flavor *signals.FlavorProfile, projectPath string) error {
+	author := analyzeAuthor
+	if author == "" {
+		author = defaultAuthor()
+	}
+
+	exported := &profile.Exported{
+		Author:  author,
+		Project: projectPath,
+		Profile: flavor,
+	}
+
+	if analyzeAnonymize {
+		anonymized, err := profile.Anonymize(exported, profile.AnonymizeOptions{
+			Evidence:    analyzeRedaction,
+			Coarsen:     analyzeCoarsen,
+			ProjectRoot: projectPath,
+		})
+		if err != nil {
+			return err
+		}
+		exported = anonymized
+	}
+
+	if err := profile.Save(analyzeExport, exported); err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported profile to %s\n", analyzeExport)
+	return nil
+}
+
+func defaultAuthor() string {
+	if out, err := exec.Command("git", "config", "--get", "user.name").Output(); err == nil {
+		if name := strings.TrimSpace(string(out)); name != "" {
+			return name
+		}
+	}
+	return os.Getenv("USER")
+}
+
+func runGlobalAnalyze(cmd *cobra.Command) error {
+	cfg, _, err := loadConfig(cmd, "", analyzeSet)
+	if err != nil {
+		return err
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %w", err)
+	}
+	userDir := filepath.Join(homeDir, ".claude")
+
+	fmt.Println("Analyzing all projects")
+	fmt.Printf("Output directory: %s/%s/\n", userDir, cfg.Output.Dir)
+
+	p, err := parser.NewParser()
+	if err != nil {
+		return fmt.Errorf("failed to create parser: %w", err)
+	}
+
+	projects, err := p.ListProjects()
+	if err != nil {
+		return err
+	}
+
+	count, first, last, err := p.GetGlobalStats()
+	if err != nil {
+		return fmt.Errorf("failed to get global stats: %w", err)
+	}
+
+	if count > 0 {
+		fmt.Printf("Found %d Claude Code messages across %d projects from %s to %s\n", count, len(projects), first.Format("2006-01-02"), last.Format("2006-01-02"))
+	}
+
+	since := analysisSince()
+
+	entries, err := fetchFromSources(p, cfg, parser.SourceFilter{Since: since})
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		return fmt.Errorf("no chat history found")
+	}
+
+	projectOf := projectRootOf()
+	profile, sigs := buildProfile(cfg, entries, projectOf)
+
+	if analyzeHTML != "" {
+		usage := stats.Compute(entries, sigs, stats.Options{TopN: 10, ProjectOf: projectOf})
+		if err := writeHTMLReport(cfg, "All projects", profile, sigs, usage); err != nil {
+			return err
+		}
 	}
 
-	entries, err := p.FetchEntriesForProject(projectPath, since)
+	userProfile, projectProfiles := aggregator.SplitByProjects(profile, cfg.Aggregator.GlobalMinProjects)
+
+	fmt.Printf("Rules shared by at least %d projects are user-level flavor\n", cfg.Aggregator.GlobalMinProjects)
+	if err := writeFlavor(cfg, userProfile, userDir, applyTargets(analyzeApply, analyzeTarget)); err != nil {
+		return err
+	}
+
+	projectNames := make([]string, 0, len(projectProfiles))
+	for project := range projectProfiles {
+		projectNames = append(projectNames, project)
+	}
+	sort.Strings(projectNames)
+
+	fmt.Printf("Project-specific rules:\n")
+	for _, project := range projectNames {
+		pp := projectProfiles[project]
+		fmt.Printf("  %s\n", project)
+		for _, pref := range allPreferences(pp) {
+			fmt.Printf("    - [%s] %s (%s)\n", pref.Category, truncateLine(pref.Value, 80), strings.Join(pref.Projects, ", "))
+		}
+		for _, conflict := range pp.Conflicts {
+			fmt.Printf("    - [conflict] %s (%s)\n", conflict.Key, strings.Join(conflict.Projects, ", "))
+		}
+	}
+
+	return nil
+}
+
+func fetchFromSources(p *parser.Parser, cfg config.Config, filter parser.SourceFilter) ([]*parser.ParsedEntry, error) {
+	sources, err := p.Sources(cfg.Sources)
 	if err != nil {
-		return fmt.Errorf("failed to fetch entries: %w", err)
+		return nil, err
 	}
 
+	entries, counts, err := parser.FetchFromSources(sources, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch entries: %w", err)
+	}
+
+	for _, source := range sources {
+		if n := counts[source.Name()]; n > 0 {
+			fmt.Printf("  - %d entries from %s\n", n, source.Name())
+		}
+	}
+
+	return entries, nil
+}
+
+func analysisSince() time.Time {
+	if analyzeAll {
+		fmt.Println("Analyzing all history")
+		return time.Time{}
+	}
+
+	since := time.Now().AddDate(0, 0, -analyzeDays)
+	fmt.Printf("Analyzing last %d days (since %s)\n", analyzeDays, since.Format("2006-01-02"))
+	return since
+}
+
+// buildProfile detects, classifies and aggregates the signals of entries.
+// Each signal is attributed to the project projectOf maps its working
+// directory to, so that sessions started in different subdirectories of one
+// repository count as one project.
+func buildProfile(cfg config.Config, entries []*parser.ParsedEntry, projectOf func(string) string) (*signals.FlavorProfile, []signals.Signal) {
 	fmt.Printf("Fetched %d entries for analysis\n", len(entries))
 
-	detector := signals.NewDetector()
+	detector := signals.NewDetector(cfg.Detector)
 	sigs := detector.DetectSignals(entries)
+	for i := range sigs {
+		if sigs[i].Project != "" {
+			sigs[i].Project = projectOf(sigs[i].Project)
+		}
+	}
 
 	fmt.Printf("Detected %d signals\n", len(sigs))
 
-	agg := aggregator.NewAggregator(aggregator.DefaultConfig())
+	sigs, quality := signals.NewClassifier(cfg.Detector.Quality).Classify(sigs)
+	if quality.Dropped > 0 || quality.Demoted > 0 {
+		fmt.Printf("Dropped %d and demoted %d corrections that read as one-off task instructions\n", quality.Dropped, quality.Demoted)
+	}
+
+	agg := aggregator.NewAggregator(cfg.Aggregator)
 	profile := agg.Aggregate(sigs)
 
 	fmt.Printf("Aggregated into profile with:\n")
@@ -92,28 +372,82 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 	fmt.Printf("  - %d corrections\n", len(profile.Corrections))
 	fmt.Printf("  - %d approvals\n", len(profile.Approvals))
 	fmt.Printf("  - %d conflicts\n", len(profile.Conflicts))
+	fmt.Printf("  - %d superseded\n", len(profile.Superseded))
+
+	return profile, sigs
+}
+
+func writeHTMLReport(cfg config.Config, name string, profile *signals.FlavorProfile, sigs []signals.Signal, usage []stats.Report) error {
+	report := output.Report{
+		Title:   "Flavor Report: " + name,
+		Profile: profile,
+		Signals: sigs,
+		Stats:   usage,
+	}
 
-	gen := output.NewGenerator(projectPath)
+	if err := output.WriteHTMLFile(analyzeHTML, report, cfg.Output); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote HTML report to %s\n", analyzeHTML)
+	return nil
+}
+
+func writeFlavor(cfg config.Config, profile *signals.FlavorProfile, targetDir string, targets []string) error {
+	gen := output.NewGenerator(targetDir, cfg.Output)
 	files, err := gen.Generate(profile)
 	if err != nil {
 		return fmt.Errorf("failed to generate output: %w", err)
 	}
 
-	fmt.Printf("Generated %d flavor files in .flavor/\n", len(files))
+	fmt.Printf("Generated %d flavor files in %s/\n", len(files), cfg.Output.Dir)
 	for _, f := range files {
 		fmt.Printf("  - %s\n", f)
 	}
 
-	if analyzeApply {
-		if err := gen.AppendToClaudeMD(profile, projectPath); err != nil {
-			return fmt.Errorf("failed to append to CLAUDE.md: %w", err)
+	if len(targets) > 0 {
+		scopeRoot := parser.ResolveProject(targetDir).Root()
+		paths, warnings, err := gen.ApplyTargets(profile, targetDir, scopeRoot, targets)
+		if err != nil {
+			return fmt.Errorf("failed to write instruction files: %w", err)
+		}
+		for _, path := range paths {
+			fmt.Printf("Wrote flavor rules to %s\n", path)
+		}
+		for _, warning := range warnings {
+			fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
 		}
-		fmt.Printf("Appended flavor rules to %s/CLAUDE.md\n", projectPath)
 	}
 
 	return nil
 }
 
+// applyTargets combines --apply, which predates output targets and means the
+// claude target, with the targets chosen through --target.
+func applyTargets(apply bool, targets []string) []string {
+	if apply && !slices.Contains(targets, "claude") {
+		targets = append([]string{"claude"}, targets...)
+	}
+	return targets
+}
+
+func allPreferences(profile *signals.FlavorProfile) []signals.Preference {
+	var prefs []signals.Preference
+	prefs = append(prefs, profile.StackPreferences...)
+	prefs = append(prefs, profile.StylePreferences...)
+	prefs = append(prefs, profile.Corrections...)
+	prefs = append(prefs, profile.Approvals...)
+	return prefs
+}
+
+func truncateLine(s string, maxLen int) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	if len(s) > maxLen {
+		return s[:maxLen] + "..."
+	}
+	return s
+}
+
 func resolveProjectPath(path string) (string, error) {
 	if path == "" {
 		return os.Getwd()
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/strrl/auto-flavor/internal/config"
+)
+
+var (
+	configFile       string
+	configPath       string
+	configSet        []string
+	configInitForce  bool
+	configInitGlobal bool
+)
+
+var configFlagKeys = map[string]string{
+	"min-signals":        "aggregator.min_signal_count",
+	"conflict-threshold": "aggregator.conflict_threshold",
+	"recency-decay-days": "aggregator.recency_decay_days",
+	"recency-model":      "aggregator.recency_model",
+	"output-dir":         "output.dir",
+	"layout":             "output.layout",
+	"apply-strategy":     "output.apply_strategy",
+	"claude-file":        "output.claude_file",
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect and initialize auto-flavor configuration",
+	Long: `Configuration is layered, later layers overriding earlier ones:
+
+  1. built-in defaults
+  2. global config:  ~/.config/auto-flavor/config.yaml
+  3. project config: <project>/.flavor/config.yaml
+  4. file passed with --config
+  5. environment variables, e.g. AUTO_FLAVOR_AGGREGATOR_MIN_SIGNAL_COUNT=3
+  6. command-line flags and --set key=value overrides`,
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective configuration",
+	RunE:  runConfigShow,
+}
+
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Write a config file populated with the defaults",
+	RunE:  runConfigInit,
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configInitCmd)
+
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Additional config file to load")
+
+	configCmd.PersistentFlags().StringVarP(&configPath, "path", "p", "", "Project path whose config to use (default: current directory)")
+	configShowCmd.Flags().StringArrayVar(&configSet, "set", nil, "Override a setting (key=value), may be repeated")
+	configInitCmd.Flags().BoolVar(&configInitForce, "force", false, "Overwrite an existing config file")
+	configInitCmd.Flags().BoolVar(&configInitGlobal, "global", false, "Write the global config instead of the project config")
+}
+
+func loadConfig(cmd *cobra.Command, projectPath string, overrides []string) (config.Config, []string, error) {
+	var flagOverrides []string
+	for flagName, key := range configFlagKeys {
+		flag := cmd.Flags().Lookup(flagName)
+		if flag != nil && flag.Changed {
+			flagOverrides = append(flagOverrides, key+"="+flag.Value.String())
+		}
+	}
+
+	loader := config.Loader{
+		ProjectDir: projectPath,
+		ExtraFile:  configFile,
+		Overrides:  append(flagOverrides, overrides...),
+	}
+
+	cfg, sources, err := loader.Load()
+	if err != nil {
+		return cfg, sources, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	return cfg, sources, nil
+}
+
+func runConfigShow(cmd *cobra.Command, args []string) error {
+	projectPath, err := resolveProjectPath(configPath)
+	if err != nil {
+		return err
+	}
+
+	cfg, sources, err := loadConfig(cmd, projectPath, configSet)
+	if err != nil {
+		return err
+	}
+
+	data, err := cfg.Marshal()
+	if err != nil {
+		return err
+	}
+
+	if len(sources) == 0 {
+		fmt.Println("# sources: built-in defaults")
+	} else {
+		fmt.Printf("# sources: defaults, %s\n", strings.Join(sources, ", "))
+	}
+	fmt.Print(string(data))
+
+	return nil
+}
+
+func runConfigInit(cmd *cobra.Command, args []string) error {
+	var path string
+	if configInitGlobal {
+		globalPath, err := config.GlobalPath()
+		if err != nil {
+			return err
+		}
+		path = globalPath
+	} else {
+		projectPath, err := resolveProjectPath(configPath)
+		if err != nil {
+			return err
+		}
+		path = config.ProjectPath(projectPath)
+	}
+
+	if err := config.Write(path, config.Default(), configInitForce); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote default config to %s\n", path)
+	return nil
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/strrl/auto-flavor/internal/output"
+	"github.com/strrl/auto-flavor/internal/parser"
+	"github.com/strrl/auto-flavor/internal/profile"
+)
+
+var (
+	explainPath    string
+	explainContext int
+	explainSet     []string
+)
+
+var explainCmd = &cobra.Command{
+	Use:   "explain <rule-id>",
+	Short: "Show the conversations a flavor rule was derived from",
+	Long: `Print the supporting evidence for a rule produced by "analyze", together with
+the surrounding conversation from the original chat history. The rule ID is the
+flavor file name without its .md extension, e.g. correction-requirement-use-pnpm.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runExplain,
+}
+
+func init() {
+	rootCmd.AddCommand(explainCmd)
+
+	explainCmd.Flags().StringVarP(&explainPath, "path", "p", "", "Path to the analyzed project (default: current directory)")
+	explainCmd.Flags().IntVarP(&explainContext, "context", "C", 4, "Number of surrounding history lines to show around each piece of evidence")
+	explainCmd.Flags().StringArrayVar(&explainSet, "set", nil, "Override a config setting (key=value), may be repeated")
+}
+
+func runExplain(cmd *cobra.Command, args []string) error {
+	projectPath, err := resolveProjectPath(explainPath)
+	if err != nil {
+		return err
+	}
+
+	cfg, _, err := loadConfig(cmd, projectPath, explainSet)
+	if err != nil {
+		return err
+	}
+
+	profilePath := filepath.Join(projectPath, cfg.Output.Dir, output.ProfileFile)
+	exported, err := profile.Load(profilePath)
+	if err != nil {
+		return fmt.Errorf("no analyzed profile found, run analyze first: %w", err)
+	}
+
+	var rule *output.Rule
+	for _, r := range output.Rules(exported.Profile) {
+		if r.ID == args[0] {
+			rule = &r
+			break
+		}
+	}
+	if rule == nil {
+		return fmt.Errorf("rule not found: %s", args[0])
+	}
+
+	pref := rule.Preference
+	fmt.Printf("# %s\n\n", rule.ID)
+	fmt.Printf("Rule:       %s\n", pref.Value)
+	fmt.Printf("Category:   %s\n", pref.Category)
+	fmt.Printf("Confidence: %.2f (recency %.2f, frequency %.2f, strength %.2f, session spread %.2f)\n",
+		pref.Confidence, pref.Breakdown.Recency, pref.Breakdown.Frequency, pref.Breakdown.Strength, pref.Breakdown.SessionSpread)
+	fmt.Printf("Seen:       %d times in %d sessions\n\n", pref.SignalCount, pref.SessionCount)
+
+	for i, e := range pref.Evidence {
+		fmt.Printf("## Evidence %d of %d: %s\n", i+1, len(pref.Evidence), e.Timestamp.Format("2006-01-02 15:04"))
+		fmt.Printf("Session %s, message %s\n", e.SessionID, e.MessageUUID)
+		fmt.Printf("Source  %s:%d\n\n", e.SourceFile, e.SourceLine)
+
+		if e.SourceFile == "" || e.SourceLine == 0 {
+			fmt.Printf("  %s\n\n", e.Text)
+			continue
+		}
+
+		entries, err := parser.ReadEntriesAround(e.SourceFile, e.SourceLine, explainContext, explainContext, e.SessionID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+			fmt.Printf("  %s\n\n", e.Text)
+			continue
+		}
+
+		output.WriteTranscript(os.Stdout, entries, output.TranscriptOptions{
+			Highlight:   map[string]string{e.MessageUUID: "signal: " + pref.Category},
+			TruncateLen: 2000,
+		})
+	}
+
+	return nil
+}
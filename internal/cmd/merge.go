@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/strrl/auto-flavor/internal/output"
+	"github.com/strrl/auto-flavor/internal/profile"
+	"github.com/strrl/auto-flavor/internal/team"
+)
+
+var (
+	mergeQuorum float64
+	mergeOutput string
+	mergePath   string
+	mergeApply  bool
+	mergeTarget []string
+	mergeSet    []string
+)
+
+var mergeCmd = &cobra.Command{
+	Use:   "merge <profile.json>...",
+	Short: "Merge exported developer profiles into a team flavor",
+	Long: `Merge several profiles exported with "analyze --export" into a team profile.
+
+Rules held by at least the quorum of developers become team rules. When the
+quorum holds a rule but disagrees on its value, it becomes a team conflict that
+lists which members hold each value. Rules below the quorum are personal
+outliers: they are reported but never written to shared instruction files.`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: runMerge,
+}
+
+func init() {
+	rootCmd.AddCommand(mergeCmd)
+
+	mergeCmd.Flags().Float64Var(&mergeQuorum, "quorum", 0.5, "Fraction of members that must share a rule for it to become a team rule")
+	mergeCmd.Flags().StringVarP(&mergeOutput, "output", "o", "", "Write the merged team profile as JSON to this file")
+	mergeCmd.Flags().StringVarP(&mergePath, "path", "p", "", "Project to write team flavor files into (default: current directory)")
+	mergeCmd.Flags().BoolVar(&mergeApply, "apply", false, "Also write team rules to the project's CLAUDE.md (same as --target claude)")
+	mergeCmd.Flags().StringSliceVar(&mergeTarget, "target", nil, "Write team rules to these assistants' instruction files: "+strings.Join(output.TargetNames(), ", "))
+	mergeCmd.Flags().String("apply-strategy", "", "How rules reach CLAUDE.md: inline, import (of FLAVOR.md) or auto")
+	mergeCmd.Flags().String("claude-file", "", "CLAUDE.md to apply rules to, relative to the project, e.g. services/api/CLAUDE.md")
+	mergeCmd.Flags().StringArrayVar(&mergeSet, "set", nil, "Override a config setting (key=value), may be repeated")
+}
+
+func runMerge(cmd *cobra.Command, args []string) error {
+	if mergeQuorum <= 0 || mergeQuorum > 1 {
+		return fmt.Errorf("quorum must be in (0, 1], got %v", mergeQuorum)
+	}
+
+	projectPath, err := resolveProjectPath(mergePath)
+	if err != nil {
+		return err
+	}
+
+	cfg, _, err := loadConfig(cmd, projectPath, mergeSet)
+	if err != nil {
+		return err
+	}
+
+	var members []team.Member
+	seen := make(map[string]int)
+	for _, path := range args {
+		exported, err := profile.Load(path)
+		if err != nil {
+			return err
+		}
+
+		name := exported.Author
+		if name == "" {
+			name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+		seen[name]++
+		if seen[name] > 1 {
+			name = fmt.Sprintf("%s#%d", name, seen[name])
+		}
+
+		members = append(members, team.Member{Name: name, Profile: exported.Profile})
+	}
+
+	fmt.Printf("Merging %d profiles: %s\n", len(members), strings.Join(memberNames(members), ", "))
+
+	merged := team.Merge(members, mergeQuorum)
+
+	fmt.Printf("Team profile with:\n")
+	fmt.Printf("  - %d stack preferences\n", len(merged.Profile.StackPreferences))
+	fmt.Printf("  - %d style preferences\n", len(merged.Profile.StylePreferences))
+	fmt.Printf("  - %d corrections\n", len(merged.Profile.Corrections))
+	fmt.Printf("  - %d approvals\n", len(merged.Profile.Approvals))
+	fmt.Printf("  - %d team conflicts\n", len(merged.Profile.Conflicts))
+	fmt.Printf("  - %d superseded\n", len(merged.Profile.Superseded))
+
+	if len(merged.Outliers) > 0 {
+		fmt.Printf("Personal outliers kept out of the team flavor:\n")
+		for _, o := range merged.Outliers {
+			fmt.Printf("  - %s: [%s] %s\n", o.Member, o.Kind, truncateLine(o.Preference.Value, 80))
+		}
+	}
+
+	if mergeOutput != "" {
+		exported := &profile.Exported{
+			Author:  "team",
+			Project: projectPath,
+			Profile: merged.Profile,
+		}
+		if err := profile.Save(mergeOutput, exported); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote team profile to %s\n", mergeOutput)
+	}
+
+	return writeFlavor(cfg, merged.Profile, projectPath, applyTargets(mergeApply, mergeTarget))
+}
+
+func memberNames(members []team.Member) []string {
+	names := make([]string, len(members))
+	for i, m := range members {
+		names[i] = m.Name
+	}
+	return names
+}
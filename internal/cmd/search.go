@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/strrl/auto-flavor/internal/parser"
+	"github.com/strrl/auto-flavor/internal/search"
+)
+
+var (
+	searchProject string
+	searchSince   string
+	searchUntil   string
+	searchRole    string
+	searchTool    string
+	searchLimit   int
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search chat history across projects",
+	Long: `Search user text, assistant text and tool inputs in the recorded chat history
+of all projects. Every word of the query must appear in a message for it to
+match; results are ranked by relevance and printed with their session, time
+and source location. An index of the words in each history file is kept in the
+user cache directory and refreshed for files that changed since the last search.
+
+Examples:
+  auto-flavor search pnpm workspace
+  auto-flavor search --project . --since 2025-01-01 "go test"
+  auto-flavor search --tool Bash docker`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runSearch,
+}
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+
+	searchCmd.Flags().StringVarP(&searchProject, "project", "p", "", "Only search sessions of this project")
+	searchCmd.Flags().StringVar(&searchSince, "since", "", "Only search messages on or after this date (YYYY-MM-DD)")
+	searchCmd.Flags().StringVar(&searchUntil, "until", "", "Only search messages before the end of this date (YYYY-MM-DD)")
+	searchCmd.Flags().StringVar(&searchRole, "role", "", "Only search messages from this role: user or assistant")
+	searchCmd.Flags().StringVar(&searchTool, "tool", "", "Only search inputs of this tool, e.g. Bash or Edit")
+	searchCmd.Flags().IntVarP(&searchLimit, "limit", "n", 20, "Maximum number of results (0 for all)")
+}
+
+func runSearch(cmd *cobra.Command, args []string) error {
+	terms := strings.Fields(strings.Join(args, " "))
+	if len(terms) == 0 {
+		return fmt.Errorf("empty search query")
+	}
+
+	filter := parser.SearchFilter{
+		Terms: terms,
+		Role:  searchRole,
+	}
+
+	switch searchRole {
+	case "", "user", "assistant":
+	default:
+		return fmt.Errorf("unknown role %q (want user or assistant)", searchRole)
+	}
+
+	if searchTool != "" {
+		if searchRole == "user" {
+			return fmt.Errorf("--tool cannot be combined with --role user")
+		}
+		filter.Role = "assistant"
+	}
+
+	var err error
+	if filter.Since, err = parseDate(searchSince); err != nil {
+		return err
+	}
+	if filter.Until, err = parseDate(searchUntil); err != nil {
+		return err
+	}
+	if !filter.Until.IsZero() {
+		filter.Until = filter.Until.AddDate(0, 0, 1)
+	}
+
+	p, err := parser.NewParser()
+	if err != nil {
+		return fmt.Errorf("failed to create parser: %w", err)
+	}
+
+	if searchProject != "" {
+		projectPath, err := resolveProjectPath(searchProject)
+		if err != nil {
+			return err
+		}
+
+		filter.Paths, err = p.ResolveProjectPaths(projectPath, parser.MatchOptions{
+			IncludeSubdirs: true,
+			MatchGitRoot:   true,
+			MatchRemote:    true,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to resolve project paths: %w", err)
+		}
+		if len(filter.Paths) == 0 {
+			return fmt.Errorf("no chat history found for project: %s", projectPath)
+		}
+	}
+
+	entries, err := p.SearchEntries(filter)
+	if err != nil {
+		return fmt.Errorf("failed to search history: %w", err)
+	}
+
+	matches := search.Search(entries, terms, search.Options{
+		Tool:       searchTool,
+		SnippetLen: 160,
+		Limit:      searchLimit,
+	})
+
+	if len(matches) == 0 {
+		fmt.Println("No matches found")
+		return nil
+	}
+
+	for _, m := range matches {
+		fmt.Printf("%s  %s  %s  %s\n", m.Entry.Timestamp.Format("2006-01-02 15:04"), shortID(m.Entry.SessionID), m.Field, m.Entry.CWD)
+		fmt.Printf("    %s\n", m.Snippet)
+		if m.Entry.SourceFile != "" {
+			fmt.Printf("    %s:%d\n", m.Entry.SourceFile, m.Entry.SourceLine)
+		}
+		fmt.Println()
+	}
+
+	fmt.Printf("%d matches, use \"auto-flavor show <session>\" to read a session\n", len(matches))
+	return nil
+}
+
+func parseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q (want YYYY-MM-DD): %w", value, err)
+	}
+	return date, nil
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/strrl/auto-flavor/internal/parser"
+)
+
+var (
+	sessionsPath        string
+	sessionsAllProjects bool
+	sessionsLimit       int
+)
+
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "List recorded chat sessions for a project",
+	Long: `List the Claude Code sessions recorded for a project with their message counts
+and time spans, most recent first. Use "show <session-id>" to read one.`,
+	RunE: runSessions,
+}
+
+func init() {
+	rootCmd.AddCommand(sessionsCmd)
+
+	sessionsCmd.Flags().StringVarP(&sessionsPath, "path", "p", "", "Path to the project (default: current directory)")
+	sessionsCmd.Flags().BoolVar(&sessionsAllProjects, "all-projects", false, "List sessions from every project")
+	sessionsCmd.Flags().IntVarP(&sessionsLimit, "limit", "n", 0, "Show at most this many sessions (0 for all)")
+}
+
+func runSessions(cmd *cobra.Command, args []string) error {
+	p, err := parser.NewParser()
+	if err != nil {
+		return fmt.Errorf("failed to create parser: %w", err)
+	}
+
+	var paths []string
+	if !sessionsAllProjects {
+		projectPath, err := resolveProjectPath(sessionsPath)
+		if err != nil {
+			return err
+		}
+
+		paths, err = p.ResolveProjectPaths(projectPath, parser.MatchOptions{
+			IncludeSubdirs: true,
+			MatchGitRoot:   true,
+			MatchRemote:    true,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to resolve project paths: %w", err)
+		}
+		if len(paths) == 0 {
+			return fmt.Errorf("no chat history found for project: %s", projectPath)
+		}
+	}
+
+	sessions, err := p.ListSessions(paths)
+	if err != nil {
+		return err
+	}
+
+	if len(sessions) == 0 {
+		fmt.Println("No sessions found")
+		return nil
+	}
+
+	if sessionsLimit > 0 && len(sessions) > sessionsLimit {
+		sessions = sessions[:sessionsLimit]
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SESSION\tSTARTED\tDURATION\tUSER\tASSISTANT\tPROJECT")
+	for _, s := range sessions {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%s\n",
+			s.SessionID,
+			s.Start.Format("2006-01-02 15:04"),
+			s.Duration().Round(1e9),
+			s.UserCount,
+			s.AssistantCount,
+			s.Project,
+		)
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/strrl/auto-flavor/internal/output"
+	"github.com/strrl/auto-flavor/internal/parser"
+	"github.com/strrl/auto-flavor/internal/signals"
+)
+
+var (
+	showTruncate int
+	showSignals  bool
+	showSet      []string
+)
+
+var showCmd = &cobra.Command{
+	Use:   "show <session-id>",
+	Short: "Render a chat session as a readable transcript",
+	Long: `Render a recorded session as a readable transcript: user and assistant text,
+tool calls with their inputs and tool results. Messages where the detector finds
+a signal are marked with ">" and annotated with what was detected. A unique
+prefix of the session ID is enough.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runShow,
+}
+
+func init() {
+	rootCmd.AddCommand(showCmd)
+
+	showCmd.Flags().IntVar(&showTruncate, "truncate", 2000, "Truncate each message block to this many characters (0 for no limit)")
+	showCmd.Flags().BoolVar(&showSignals, "signals", true, "Highlight messages where signals were detected")
+	showCmd.Flags().StringArrayVar(&showSet, "set", nil, "Override a config setting (key=value), may be repeated")
+}
+
+func runShow(cmd *cobra.Command, args []string) error {
+	p, err := parser.NewParser()
+	if err != nil {
+		return fmt.Errorf("failed to create parser: %w", err)
+	}
+
+	entries, err := p.FetchSession(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to fetch session: %w", err)
+	}
+
+	if len(entries) == 0 {
+		return fmt.Errorf("session not found: %s", args[0])
+	}
+
+	ids := make(map[string]struct{})
+	for _, entry := range entries {
+		ids[entry.SessionID] = struct{}{}
+	}
+	if len(ids) > 1 {
+		return fmt.Errorf("session ID prefix %q is ambiguous, matches %d sessions", args[0], len(ids))
+	}
+
+	first, last := entries[0], entries[len(entries)-1]
+	fmt.Printf("# Session %s\n", first.SessionID)
+	fmt.Printf("Project: %s\n", first.CWD)
+	fmt.Printf("Time:    %s to %s\n", first.Timestamp.Format("2006-01-02 15:04"), last.Timestamp.Format("2006-01-02 15:04"))
+	fmt.Printf("Source:  %s\n\n", first.SourceFile)
+
+	highlight := make(map[string]string)
+	if showSignals {
+		cfg, _, err := loadConfig(cmd, first.CWD, showSet)
+		if err != nil {
+			return err
+		}
+
+		sigs := signals.NewDetector(cfg.Detector).DetectSignals(entries)
+		sigs, _ = signals.NewClassifier(cfg.Detector.Quality).Classify(sigs)
+
+		notes := make(map[string][]string)
+		for _, sig := range sigs {
+			notes[sig.MessageUUID] = append(notes[sig.MessageUUID], fmt.Sprintf("%s/%s: %s", sig.Type, sig.Category, truncateLine(sig.Value, 80)))
+		}
+		for uuid, n := range notes {
+			highlight[uuid] = "signal " + strings.Join(n, "; ")
+		}
+
+		fmt.Printf("Detected %d signals in %d messages\n\n", countNotes(notes), len(notes))
+	}
+
+	output.WriteTranscript(os.Stdout, entries, output.TranscriptOptions{
+		Highlight:   highlight,
+		TruncateLen: showTruncate,
+	})
+
+	return nil
+}
+
+func countNotes(notes map[string][]string) int {
+	count := 0
+	for _, n := range notes {
+		count += len(n)
+	}
+	return count
+}
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/strrl/auto-flavor/internal/parser"
+	"github.com/strrl/auto-flavor/internal/query"
+	"github.com/strrl/auto-flavor/internal/signals"
+)
+
+var (
+	sqlProject     string
+	sqlSince       string
+	sqlFormat      string
+	sqlWidth       int
+	sqlInteractive bool
+	sqlSet         []string
+)
+
+var sqlCmd = &cobra.Command{
+	Use:   "sql [query]",
+	Short: "Run read-only SQL over the chat history",
+	Long: `Load the chat history into normalized tables and run read-only SQL against
+them. Without a query, or with --interactive, an interactive prompt is started.
+
+Tables:
+  entries       uuid, parent_uuid, session_id, project, type, timestamp, text,
+                source_file, source_line
+  tool_uses     id, uuid, session_id, project, timestamp, name, input (JSON)
+  tool_results  tool_use_id, uuid, session_id, project, timestamp, is_error,
+                content
+  sessions      session_id, project, started_at, ended_at, duration,
+                user_messages, assistant_messages, tool_uses, signals
+  signals       type, category, key, value, strength, timestamp, session_id,
+                project, message_uuid, context
+
+Examples:
+  auto-flavor sql "SELECT name, COUNT(*) FROM tool_uses GROUP BY name ORDER BY 2 DESC"
+  auto-flavor sql -f csv "SELECT * FROM signals WHERE type = 'correction'"`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runSQL,
+}
+
+func init() {
+	rootCmd.AddCommand(sqlCmd)
+
+	sqlCmd.Flags().StringVarP(&sqlProject, "project", "p", "", "Only load sessions of this project (default: all projects)")
+	sqlCmd.Flags().StringVar(&sqlSince, "since", "", "Only load messages on or after this date (YYYY-MM-DD)")
+	sqlCmd.Flags().StringVarP(&sqlFormat, "format", "f", query.FormatTable, "Output format: table, csv or json")
+	sqlCmd.Flags().IntVar(&sqlWidth, "width", 60, "Truncate table cells to this many characters (0 for no limit)")
+	sqlCmd.Flags().BoolVarP(&sqlInteractive, "interactive", "i", false, "Start an interactive prompt")
+	sqlCmd.Flags().StringArrayVar(&sqlSet, "set", nil, "Override a config setting (key=value), may be repeated")
+}
+
+func runSQL(cmd *cobra.Command, args []string) error {
+	since, err := parseDate(sqlSince)
+	if err != nil {
+		return err
+	}
+
+	p, err := parser.NewParser()
+	if err != nil {
+		return fmt.Errorf("failed to create parser: %w", err)
+	}
+
+	var (
+		entries     []*parser.ParsedEntry
+		projectPath string
+	)
+	if sqlProject != "" {
+		projectPath, err = resolveProjectPath(sqlProject)
+		if err != nil {
+			return err
+		}
+
+		paths, err := p.ResolveProjectPaths(projectPath, parser.MatchOptions{
+			IncludeSubdirs: true,
+			MatchGitRoot:   true,
+			MatchRemote:    true,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to resolve project paths: %w", err)
+		}
+
+		entries, err = p.FetchEntriesForPaths(paths, since)
+		if err != nil {
+			return fmt.Errorf("failed to fetch entries: %w", err)
+		}
+	} else {
+		entries, err = p.FetchAllEntries(since)
+		if err != nil {
+			return fmt.Errorf("failed to fetch entries: %w", err)
+		}
+	}
+
+	cfg, _, err := loadConfig(cmd, projectPath, sqlSet)
+	if err != nil {
+		return err
+	}
+
+	sigs := signals.NewDetector(cfg.Detector).DetectSignals(entries)
+	sigs, _ = signals.NewClassifier(cfg.Detector.Quality).Classify(sigs)
+
+	engine, err := query.NewEngine(entries, sigs)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 1 && !sqlInteractive {
+		result, err := engine.Run(args[0])
+		if err != nil {
+			return err
+		}
+		return query.Write(os.Stdout, result, sqlFormat, sqlWidth)
+	}
+
+	fmt.Fprintf(os.Stderr, "Loaded %d entries and %d signals. End statements with \";\", type .help for commands.\n", len(entries), len(sigs))
+	return runSQLPrompt(engine, os.Stdin, os.Stdout)
+}
+
+func runSQLPrompt(engine *query.Engine, in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	format := sqlFormat
+	var buf strings.Builder
+
+	for {
+		if buf.Len() == 0 {
+			fmt.Fprint(out, "sql> ")
+		} else {
+			fmt.Fprint(out, "...> ")
+		}
+
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return scanner.Err()
+		}
+		line := scanner.Text()
+
+		if buf.Len() == 0 && strings.HasPrefix(strings.TrimSpace(line), ".") {
+			fields := strings.Fields(line)
+			switch fields[0] {
+			case ".quit", ".exit":
+				return nil
+			case ".tables":
+				for _, view := range query.Views {
+					fmt.Fprintf(out, "%-14s %s\n", view.Name, view.Description)
+				}
+			case ".format":
+				if len(fields) != 2 {
+					fmt.Fprintf(out, "current format: %s\n", format)
+					continue
+				}
+				format = fields[1]
+			case ".help":
+				fmt.Fprintln(out, ".tables            list available tables")
+				fmt.Fprintln(out, ".format [format]   show or set the output format: table, csv or json")
+				fmt.Fprintln(out, ".quit              leave the prompt")
+			default:
+				fmt.Fprintf(out, "unknown command %s, type .help for commands\n", fields[0])
+			}
+			continue
+		}
+
+		buf.WriteString(line)
+		buf.WriteString("\n")
+		if !strings.HasSuffix(strings.TrimSpace(line), ";") {
+			continue
+		}
+
+		stmt := buf.String()
+		buf.Reset()
+
+		result, err := engine.Run(stmt)
+		if err != nil {
+			fmt.Fprintf(out, "error: %v\n", err)
+			continue
+		}
+		if err := query.Write(out, result, format, sqlWidth); err != nil {
+			fmt.Fprintf(out, "error: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/strrl/auto-flavor/internal/output"
+	"github.com/strrl/auto-flavor/internal/parser"
+	"github.com/strrl/auto-flavor/internal/signals"
+	"github.com/strrl/auto-flavor/internal/stats"
+)
+
+var (
+	statsPath        string
+	statsAllProjects bool
+	statsDays        int
+	statsAll         bool
+	statsPeriod      string
+	statsTop         int
+	statsFormat      string
+	statsOutput      string
+	statsSubagents   bool
+	statsSet         []string
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Report how the assistant is used in a project",
+	Long: `Summarize assistant usage from the chat history: sessions, turns, tool usage
+and error rates, most edited files, most run commands, corrections per session
+and average session length. Reports can be split by week or month and written
+as markdown or JSON. With --subagents, the turns and tool calls of subagents
+are reported apart from the main agent's.`,
+	RunE: runStats,
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+
+	statsCmd.Flags().StringVarP(&statsPath, "path", "p", "", "Path to the project (default: current directory)")
+	statsCmd.Flags().BoolVar(&statsAllProjects, "all-projects", false, "Report on every project, one section per project")
+	statsCmd.Flags().IntVarP(&statsDays, "days", "d", 30, "Number of days to report on")
+	statsCmd.Flags().BoolVar(&statsAll, "all", false, "Report on all history regardless of time")
+	statsCmd.Flags().StringVar(&statsPeriod, "period", "", "Split reports by period: week or month")
+	statsCmd.Flags().IntVar(&statsTop, "top", 10, "Number of files and commands to list")
+	statsCmd.Flags().StringVarP(&statsFormat, "format", "f", "markdown", "Output format: markdown or json")
+	statsCmd.Flags().StringVarP(&statsOutput, "output", "o", "", "Write the report to this file instead of stdout")
+	statsCmd.Flags().BoolVar(&statsSubagents, "subagents", false, "Report subagent turns and tool calls separately from the main agent's")
+	statsCmd.Flags().StringArrayVar(&statsSet, "set", nil, "Override a config setting (key=value), may be repeated")
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	if err := stats.ValidatePeriod(statsPeriod); err != nil {
+		return err
+	}
+	if statsFormat != "markdown" && statsFormat != "json" {
+		return fmt.Errorf("unknown output format %q (want markdown or json)", statsFormat)
+	}
+
+	var since time.Time
+	if !statsAll {
+		since = time.Now().AddDate(0, 0, -statsDays)
+	}
+
+	p, err := parser.NewParser()
+	if err != nil {
+		return fmt.Errorf("failed to create parser: %w", err)
+	}
+
+	var (
+		entries     []*parser.ParsedEntry
+		projectPath string
+		projectOf   func(string) string
+	)
+
+	if statsAllProjects {
+		entries, err = p.FetchAllEntries(since)
+		if err != nil {
+			return fmt.Errorf("failed to fetch entries: %w", err)
+		}
+		projectOf = projectRootOf()
+	} else {
+		projectPath, err = resolveProjectPath(statsPath)
+		if err != nil {
+			return err
+		}
+
+		paths, err := p.ResolveProjectPaths(projectPath, parser.MatchOptions{
+			IncludeSubdirs: true,
+			MatchGitRoot:   true,
+			MatchRemote:    true,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to resolve project paths: %w", err)
+		}
+
+		entries, err = p.FetchEntriesForPaths(paths, since)
+		if err != nil {
+			return fmt.Errorf("failed to fetch entries: %w", err)
+		}
+		projectOf = func(string) string { return projectPath }
+	}
+
+	if len(entries) == 0 {
+		return fmt.Errorf("no chat history found in the selected period")
+	}
+
+	cfg, _, err := loadConfig(cmd, projectPath, statsSet)
+	if err != nil {
+		return err
+	}
+
+	sigs := signals.NewDetector(cfg.Detector).DetectSignals(entries)
+	sigs, _ = signals.NewClassifier(cfg.Detector.Quality).Classify(sigs)
+	reports := stats.Compute(entries, sigs, stats.Options{
+		Period:         statsPeriod,
+		TopN:           statsTop,
+		ProjectOf:      projectOf,
+		SplitSubagents: statsSubagents,
+	})
+
+	var w io.Writer = os.Stdout
+	if statsOutput != "" {
+		f, err := os.Create(statsOutput)
+		if err != nil {
+			return fmt.Errorf("failed to create report file: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if statsFormat == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(reports); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+	} else {
+		output.WriteStatsMarkdown(w, reports)
+	}
+
+	if statsOutput != "" {
+		fmt.Printf("Wrote usage report to %s\n", statsOutput)
+	}
+
+	return nil
+}
+
+// projectRootOf groups recorded directories under their git root so that
+// sessions started in subdirectories count towards the same project.
+func projectRootOf() func(string) string {
+	roots := make(map[string]string)
+	return func(cwd string) string {
+		if root, ok := roots[cwd]; ok {
+			return root
+		}
+		root := cwd
+		if info, err := os.Stat(cwd); err == nil && info.IsDir() {
+			root = parser.ResolveProject(cwd).Root()
+		}
+		roots[cwd] = root
+		return root
+	}
+}
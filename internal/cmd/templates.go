@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/strrl/auto-flavor/internal/output"
+)
+
+var (
+	templatesPath  string
+	templatesForce bool
+)
+
+var templatesCmd = &cobra.Command{
+	Use:   "templates",
+	Short: "Write the built-in flavor file templates for customization",
+	Long: `Flavor files are rendered with Go text/template. A template placed in
+<project>/.flavor/templates/ replaces the built-in template of the same name:
+
+  preference.md.tmpl  stack, style, correction and approval rules
+  conflict.md.tmpl    conflict-*.undecided.md files
+  superseded.md.tmpl  superseded-*.md files
+  flavor.md.tmpl      FLAVOR.md, written with --layout single or both
+
+Templates see the full data model of the rule: every field of the preference,
+conflict or superseded preference (Key, Value, Category, Confidence,
+Breakdown, Evidence, Projects, Members, ...) plus its ID; preferences also
+have Kind. FLAVOR.md sees Profile, Generated, Sections (Title, Anchor,
+Rules), Conflicts and Superseded. Evidence on preferences is limited to output.evidence_count; the
+complete list is available as .Preference.Evidence.
+
+Functions: date, span, truncate (optionally with a length), capitalize,
+join, inc and badge, in addition to the text/template builtins.
+
+This command writes the built-in templates into the templates directory,
+keeping any that already exist unless --force is given.`,
+	RunE: runTemplates,
+}
+
+func init() {
+	rootCmd.AddCommand(templatesCmd)
+
+	templatesCmd.Flags().StringVarP(&templatesPath, "path", "p", "", "Project path (default: current directory)")
+	templatesCmd.Flags().BoolVar(&templatesForce, "force", false, "Overwrite existing templates")
+}
+
+func runTemplates(cmd *cobra.Command, args []string) error {
+	projectPath, err := resolveProjectPath(templatesPath)
+	if err != nil {
+		return err
+	}
+
+	cfg, _, err := loadConfig(cmd, projectPath, nil)
+	if err != nil {
+		return err
+	}
+
+	written, err := output.WriteBuiltinTemplates(filepath.Join(projectPath, cfg.Output.Dir), templatesForce)
+	if err != nil {
+		return err
+	}
+
+	if len(written) == 0 {
+		fmt.Println("Templates already exist; use --force to overwrite them")
+		return nil
+	}
+
+	for _, path := range written {
+		fmt.Printf("Wrote %s\n", path)
+	}
+	return nil
+}
@@ -0,0 +1,331 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/strrl/auto-flavor/internal/aggregator"
+	"github.com/strrl/auto-flavor/internal/output"
+	"github.com/strrl/auto-flavor/internal/parser"
+	"github.com/strrl/auto-flavor/internal/signals"
+)
+
+const envPrefix = "AUTO_FLAVOR_"
+
+type Config struct {
+	Aggregator aggregator.Config    `yaml:"aggregator"`
+	Detector   signals.Config       `yaml:"detector"`
+	Output     output.Config        `yaml:"output"`
+	Sources    parser.SourcesConfig `yaml:"sources"`
+}
+
+func Default() Config {
+	return Config{
+		Aggregator: aggregator.DefaultConfig(),
+		Detector:   signals.DefaultConfig(),
+		Output:     output.DefaultConfig(),
+		Sources:    parser.DefaultSourcesConfig(),
+	}
+}
+
+type Loader struct {
+	ProjectDir string
+	ExtraFile  string
+	Overrides  []string
+}
+
+// Load builds the effective configuration by layering, in increasing order of
+// precedence: built-in defaults, the global config file, the project config
+// file, an explicitly requested file, AUTO_FLAVOR_* environment variables and
+// key=value overrides. It also returns the files that were actually read.
+func (l Loader) Load() (Config, []string, error) {
+	cfg := Default()
+	var sources []string
+
+	var files []string
+	if global, err := GlobalPath(); err == nil {
+		files = append(files, global)
+	}
+	if l.ProjectDir != "" {
+		files = append(files, ProjectPath(l.ProjectDir))
+	}
+
+	for _, path := range files {
+		loaded, err := cfg.mergeFile(path)
+		if err != nil {
+			return cfg, sources, err
+		}
+		if loaded {
+			sources = append(sources, path)
+		}
+	}
+
+	if l.ExtraFile != "" {
+		loaded, err := cfg.mergeFile(l.ExtraFile)
+		if err != nil {
+			return cfg, sources, err
+		}
+		if !loaded {
+			return cfg, sources, fmt.Errorf("config file not found: %s", l.ExtraFile)
+		}
+		sources = append(sources, l.ExtraFile)
+	}
+
+	if err := cfg.applyEnv(os.Environ()); err != nil {
+		return cfg, sources, err
+	}
+
+	for _, override := range l.Overrides {
+		key, value, ok := strings.Cut(override, "=")
+		if !ok {
+			return cfg, sources, fmt.Errorf("invalid override %q, expected key=value", override)
+		}
+		if err := cfg.Set(strings.TrimSpace(key), strings.TrimSpace(value)); err != nil {
+			return cfg, sources, err
+		}
+	}
+
+	if err := cfg.Detector.Validate(); err != nil {
+		return cfg, sources, fmt.Errorf("invalid detector config: %w", err)
+	}
+
+	if err := cfg.Aggregator.Validate(); err != nil {
+		return cfg, sources, fmt.Errorf("invalid aggregator config: %w", err)
+	}
+
+	if err := cfg.Output.Validate(); err != nil {
+		return cfg, sources, fmt.Errorf("invalid output config: %w", err)
+	}
+
+	return cfg, sources, nil
+}
+
+func GlobalPath() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "auto-flavor", "config.yaml"), nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	return filepath.Join(homeDir, ".config", "auto-flavor", "config.yaml"), nil
+}
+
+func ProjectPath(projectDir string) string {
+	return filepath.Join(projectDir, ".flavor", "config.yaml")
+}
+
+func (c *Config) mergeFile(path string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return false, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+
+	return true, nil
+}
+
+func (c *Config) applyEnv(environ []string) error {
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(name, envPrefix) {
+			continue
+		}
+
+		key, found := c.envKey(strings.TrimPrefix(name, envPrefix))
+		if !found {
+			continue
+		}
+
+		if err := c.Set(key, value); err != nil {
+			return fmt.Errorf("invalid value for %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) envKey(name string) (string, bool) {
+	for _, key := range Keys() {
+		if strings.ToUpper(strings.ReplaceAll(key, ".", "_")) == name {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// Keys lists every settable dotted key, such as "aggregator.min_signal_count".
+func Keys() []string {
+	var keys []string
+	walkKeys(reflect.TypeOf(Config{}), "", &keys)
+	return keys
+}
+
+func walkKeys(t reflect.Type, prefix string, keys *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := yamlName(field)
+		if name == "" {
+			continue
+		}
+
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+
+		if field.Type.Kind() == reflect.Struct {
+			walkKeys(field.Type, key, keys)
+			continue
+		}
+
+		*keys = append(*keys, key)
+	}
+}
+
+// Set assigns a single setting addressed by its dotted key. Map values are
+// given as comma-separated k=v pairs and are merged into the existing map;
+// list values are given as comma-separated items and replace the list.
+func (c *Config) Set(key, value string) error {
+	field, err := lookupField(reflect.ValueOf(c).Elem(), strings.Split(key, "."))
+	if err != nil {
+		return fmt.Errorf("unknown config key %q", key)
+	}
+
+	return setValue(field, value)
+}
+
+func lookupField(v reflect.Value, path []string) (reflect.Value, error) {
+	for i := 0; i < v.NumField(); i++ {
+		if yamlName(v.Type().Field(i)) != path[0] {
+			continue
+		}
+
+		field := v.Field(i)
+		if len(path) == 1 {
+			return field, nil
+		}
+		if field.Kind() != reflect.Struct {
+			break
+		}
+		return lookupField(field, path[1:])
+	}
+
+	return reflect.Value{}, fmt.Errorf("not found")
+}
+
+func setValue(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("expected integer: %w", err)
+		}
+		field.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("expected number: %w", err)
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("expected boolean: %w", err)
+		}
+		field.SetBool(b)
+	case reflect.Map:
+		if field.Type().Key().Kind() != reflect.String || field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported map type %s", field.Type())
+		}
+		if field.IsNil() {
+			field.Set(reflect.MakeMap(field.Type()))
+		}
+		for _, pair := range strings.Split(value, ",") {
+			k, v, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("expected k=v pairs, got %q", pair)
+			}
+			field.SetMapIndex(reflect.ValueOf(strings.TrimSpace(k)), reflect.ValueOf(strings.TrimSpace(v)))
+		}
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported list type %s", field.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported setting type %s", field.Type())
+	}
+
+	return nil
+}
+
+func yamlName(field reflect.StructField) string {
+	tag := field.Tag.Get("yaml")
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+func (c Config) Marshal() ([]byte, error) {
+	var sb strings.Builder
+	enc := yaml.NewEncoder(&sb)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(c); err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	return []byte(sb.String()), nil
+}
+
+func Write(path string, cfg Config, force bool) error {
+	if !force {
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("config already exists: %s (use --force to overwrite)", path)
+		}
+	}
+
+	data, err := cfg.Marshal()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,167 @@
+package output
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Strategies for putting rules into CLAUDE.md: inline copies the rule text,
+// import references FLAVOR.md, and auto imports whenever FLAVOR.md is part
+// of the layout.
+const (
+	ApplyAuto   = "auto"
+	ApplyInline = "inline"
+	ApplyImport = "import"
+)
+
+const (
+	claudeFile      = "CLAUDE.md"
+	claudeLocalFile = "CLAUDE.local.md"
+)
+
+// LocalClaudeFile returns the personal, git-ignored counterpart of a
+// CLAUDE.md path in the same directory.
+func LocalClaudeFile(path string) string {
+	return filepath.Join(filepath.Dir(path), claudeLocalFile)
+}
+
+// applyClaude writes into the configured CLAUDE.md, either the rules or an
+// import of FLAVOR.md. An import already present in that file or in a memory
+// file Claude Code loads along with it is left as it is, and a section of
+// ours that would duplicate it is removed. Rules scoped to a directory go to
+// a memory file of the same name there either way.
+func (g *Generator) applyClaude(t markdownTarget, dir string, rules []InstructionRule) ([]string, []string, error) {
+	t.path = g.config.ClaudeFile
+
+	if !g.config.importsFlavorFile() {
+		return t.Write(dir, rules)
+	}
+
+	path := filepath.Join(dir, t.path)
+
+	_, nested := splitByDir(rules)
+	paths, err := t.writeNested(dir, path, nested)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	doc := filepath.Join(g.outputDir, g.config.Dir, FlavorFile)
+
+	existing, err := findImport(dir, path, doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	if existing != "" {
+		return paths, nil, removeManagedSection(path)
+	}
+
+	if _, err := t.writeImport(dir, doc); err != nil {
+		return nil, nil, err
+	}
+	return append([]string{path}, paths...), nil, nil
+}
+
+// findImport looks for a memory file that imports doc: file itself, outside
+// of its auto-flavor section, its CLAUDE.local.md, and the CLAUDE.md and
+// CLAUDE.local.md of every directory between it and root.
+func findImport(root, file, doc string) (string, error) {
+	candidates := []string{file}
+
+	dir := filepath.Dir(file)
+	for {
+		for _, name := range []string{claudeFile, claudeLocalFile} {
+			if path := filepath.Join(dir, name); path != file {
+				candidates = append(candidates, path)
+			}
+		}
+		if rel, err := filepath.Rel(root, dir); err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+
+	for _, path := range candidates {
+		content, err := readOptional(path)
+		if err != nil {
+			return "", err
+		}
+		if path == file {
+			content = stripManagedSection(content)
+		}
+		for _, imported := range memoryImports(content, filepath.Dir(path)) {
+			if imported == filepath.Clean(doc) {
+				return path, nil
+			}
+		}
+	}
+
+	return "", nil
+}
+
+var memoryImport = regexp.MustCompile(`(?:^|\s)@(\S+)`)
+
+// memoryImports lists the files a memory file imports with @path, resolved
+// against dir. Like Claude Code, it ignores code spans and code blocks.
+func memoryImports(content, dir string) []string {
+	homeDir, _ := os.UserHomeDir()
+
+	var imports []string
+	inFence := false
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		for _, m := range memoryImport.FindAllStringSubmatch(stripCodeSpans(line), -1) {
+			path := m[1]
+			switch {
+			case strings.HasPrefix(path, "~/") && homeDir != "":
+				path = filepath.Join(homeDir, path[2:])
+			case !filepath.IsAbs(path):
+				path = filepath.Join(dir, path)
+			}
+			imports = append(imports, filepath.Clean(path))
+		}
+	}
+
+	return imports
+}
+
+var codeSpan = regexp.MustCompile("`[^`]*`")
+
+func stripCodeSpans(line string) string {
+	return codeSpan.ReplaceAllString(line, "")
+}
+
+func removeManagedSection(path string) error {
+	existing, err := readOptional(path)
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(existing, sectionBegin) {
+		return nil
+	}
+
+	content := strings.TrimRight(stripManagedSection(existing), "\n") + "\n"
+	if strings.TrimSpace(content) == "" {
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+		return nil
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,444 @@
+package output
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/strrl/auto-flavor/internal/signals"
+	"github.com/strrl/auto-flavor/internal/stats"
+)
+
+// Report is everything rendered into the HTML dashboard. Signals are the raw
+// detections the profile was aggregated from and drive the timelines and
+// breakdown charts; Stats is optional.
+type Report struct {
+	Title   string
+	Profile *signals.FlavorProfile
+	Signals []signals.Signal
+	Stats   []stats.Report
+}
+
+const timelineBins = 30
+
+func WriteHTMLFile(path string, report Report, cfg Config) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create report directory: %w", err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create report: %w", err)
+	}
+	defer f.Close()
+
+	if err := WriteHTML(f, report, cfg); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+// WriteHTML renders a self-contained HTML report: styles, scripts and charts
+// are inlined so the file can be shared without a server or network access.
+func WriteHTML(w io.Writer, report Report, cfg Config) error {
+	if err := htmlTemplate.Execute(w, buildHTMLView(report, cfg)); err != nil {
+		return fmt.Errorf("failed to render HTML report: %w", err)
+	}
+	return nil
+}
+
+type htmlView struct {
+	Title     string
+	Generated string
+	Range     string
+	Messages  int
+	Rules     []htmlRule
+	Conflicts []htmlConflict
+	Charts    []htmlChart
+	Stats     []stats.Report
+}
+
+type htmlRule struct {
+	ID         string
+	Kind       string
+	Category   string
+	Key        string
+	Value      string
+	Confidence float64
+	Signals    int
+	Sessions   int
+	FirstSeen  string
+	LastSeen   string
+	Timeline   template.HTML
+}
+
+type htmlConflict struct {
+	Category string
+	Key      string
+	Values   []htmlConflictValue
+}
+
+type htmlConflictValue struct {
+	Value    string
+	LastSeen string
+	Signals  int
+	Strength float64
+	Members  string
+	Evidence []string
+	IsNewest bool
+	Timeline template.HTML
+}
+
+type htmlChart struct {
+	Title string
+	SVG   template.HTML
+}
+
+func buildHTMLView(report Report, cfg Config) htmlView {
+	profile := report.Profile
+	start, end := profile.TimeRange.Start, profile.TimeRange.End
+
+	view := htmlView{
+		Title:     report.Title,
+		Generated: profile.CreatedAt.Format("2006-01-02 15:04"),
+		Range:     fmt.Sprintf("%s to %s", start.Format("2006-01-02"), end.Format("2006-01-02")),
+		Messages:  profile.AnalyzedMessages,
+		Stats:     report.Stats,
+	}
+	if view.Title == "" {
+		view.Title = "Flavor Report"
+	}
+
+	byRule := make(map[string][]signals.Signal)
+	byKey := make(map[string][]signals.Signal)
+	for _, sig := range report.Signals {
+		key := sig.Category + "::" + sig.Key
+		byRule[string(sig.Type)+"::"+key] = append(byRule[string(sig.Type)+"::"+key], sig)
+		byKey[key] = append(byKey[key], sig)
+	}
+
+	for _, rule := range Rules(profile) {
+		pref := rule.Preference
+		view.Rules = append(view.Rules, htmlRule{
+			ID:         rule.ID,
+			Kind:       rule.Kind,
+			Category:   pref.Category,
+			Key:        pref.Key,
+			Value:      truncate(pref.Value, cfg.TruncateLength),
+			Confidence: pref.Confidence,
+			Signals:    pref.SignalCount,
+			Sessions:   pref.SessionCount,
+			FirstSeen:  pref.FirstSeen.Format("2006-01-02"),
+			LastSeen:   pref.LastSeen.Format("2006-01-02"),
+			Timeline:   timelineSVG(byRule[rule.Kind+"::"+pref.Category+"::"+pref.Key], start, end),
+		})
+	}
+
+	for _, conflict := range profile.Conflicts {
+		card := htmlConflict{Category: conflict.Category, Key: conflict.Key}
+		sigs := byKey[conflict.Category+"::"+conflict.Key]
+
+		for i, v := range conflict.Values {
+			var matching []signals.Signal
+			for _, sig := range sigs {
+				if sig.Value == v.Value {
+					matching = append(matching, sig)
+				}
+			}
+
+			card.Values = append(card.Values, htmlConflictValue{
+				Value:    truncate(v.Value, cfg.TruncateLength),
+				LastSeen: v.Timestamp.Format("2006-01-02"),
+				Signals:  v.SignalCount,
+				Strength: v.Strength,
+				Members:  strings.Join(v.Members, ", "),
+				Evidence: conflictEvidence(matching, cfg),
+				IsNewest: i == 0,
+				Timeline: timelineSVG(matching, start, end),
+			})
+		}
+
+		view.Conflicts = append(view.Conflicts, card)
+	}
+
+	languages := make(map[string]int)
+	toolchain := make(map[string]int)
+	commands := make(map[string]int)
+	hooks := make(map[string]int)
+	for _, sig := range report.Signals {
+		if sig.Type != signals.SignalStack && sig.Type != signals.SignalUsage {
+			continue
+		}
+		switch sig.Category {
+		case "language":
+			languages[sig.Key]++
+		case "tool":
+			toolchain[sig.Key]++
+		case "slash_command":
+			commands[sig.Key]++
+		case "hook":
+			hooks[sig.Key]++
+		}
+	}
+
+	assistantTools := make(map[string]int)
+	for _, r := range report.Stats {
+		for _, t := range r.Tools {
+			assistantTools[t.Name] += t.Calls
+		}
+	}
+
+	for _, chart := range []struct {
+		title  string
+		counts map[string]int
+	}{
+		{"Languages", languages},
+		{"Toolchain", toolchain},
+		{"Slash commands", commands},
+		{"Hooks", hooks},
+		{"Assistant tool calls", assistantTools},
+	} {
+		if len(chart.counts) > 0 {
+			view.Charts = append(view.Charts, htmlChart{Title: chart.title, SVG: barChartSVG(chart.counts, 12)})
+		}
+	}
+
+	return view
+}
+
+func conflictEvidence(sigs []signals.Signal, cfg Config) []string {
+	sorted := append([]signals.Signal(nil), sigs...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.After(sorted[j].Timestamp)
+	})
+
+	var evidence []string
+	for _, sig := range sorted {
+		if len(evidence) >= cfg.EvidenceCount {
+			break
+		}
+		text := sig.Value
+		if sig.Context != "" {
+			text = sig.Context + " → " + sig.Value
+		}
+		evidence = append(evidence, fmt.Sprintf("%s: %s", sig.Timestamp.Format("2006-01-02"), truncate(text, cfg.TruncateLength)))
+	}
+	return evidence
+}
+
+// timelineSVG draws a small bar chart of how many signals fell into each of
+// timelineBins equal slices of the analyzed time range.
+func timelineSVG(sigs []signals.Signal, start, end time.Time) template.HTML {
+	if len(sigs) == 0 || !end.After(start) {
+		return ""
+	}
+
+	var bins [timelineBins]int
+	span := end.Sub(start)
+	peak := 0
+	for _, sig := range sigs {
+		i := int(float64(sig.Timestamp.Sub(start)) / float64(span) * timelineBins)
+		if i < 0 {
+			i = 0
+		}
+		if i >= timelineBins {
+			i = timelineBins - 1
+		}
+		bins[i]++
+		if bins[i] > peak {
+			peak = bins[i]
+		}
+	}
+
+	const width, height, barWidth = 150, 24, 5
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg class="timeline" width="%d" height="%d" viewBox="0 0 %d %d" role="img">`, width, height, width, height)
+	fmt.Fprintf(&sb, `<line x1="0" y1="%d" x2="%d" y2="%d" class="axis"/>`, height-1, width, height-1)
+	for i, count := range bins {
+		if count == 0 {
+			continue
+		}
+		h := 2 + (height-3)*count/peak
+		fmt.Fprintf(&sb, `<rect x="%d" y="%d" width="%d" height="%d"><title>%d</title></rect>`, i*barWidth, height-1-h, barWidth-1, h, count)
+	}
+	sb.WriteString(`</svg>`)
+
+	return template.HTML(sb.String())
+}
+
+func barChartSVG(counts map[string]int, limit int) template.HTML {
+	type bar struct {
+		label string
+		count int
+	}
+
+	bars := make([]bar, 0, len(counts))
+	peak := 0
+	for label, count := range counts {
+		bars = append(bars, bar{label, count})
+		if count > peak {
+			peak = count
+		}
+	}
+	sort.Slice(bars, func(i, j int) bool {
+		if bars[i].count != bars[j].count {
+			return bars[i].count > bars[j].count
+		}
+		return bars[i].label < bars[j].label
+	})
+	if len(bars) > limit {
+		bars = bars[:limit]
+	}
+
+	const width, rowHeight, labelWidth = 360, 22, 110
+	height := rowHeight * len(bars)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg class="chart" width="%d" height="%d" viewBox="0 0 %d %d" role="img">`, width, height, width, height)
+	for i, b := range bars {
+		y := i * rowHeight
+		w := (width - labelWidth - 40) * b.count / peak
+		fmt.Fprintf(&sb, `<text x="%d" y="%d" text-anchor="end">%s</text>`, labelWidth-6, y+15, template.HTMLEscapeString(b.label))
+		fmt.Fprintf(&sb, `<rect x="%d" y="%d" width="%d" height="%d"/>`, labelWidth, y+4, max(w, 1), rowHeight-8)
+		fmt.Fprintf(&sb, `<text x="%d" y="%d" class="count">%d</text>`, labelWidth+max(w, 1)+4, y+15, b.count)
+	}
+	sb.WriteString(`</svg>`)
+
+	return template.HTML(sb.String())
+}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font: 14px/1.45 -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 0; color: #1f2328; background: #f6f8fa; }
+main { max-width: 1200px; margin: 0 auto; padding: 24px; }
+h1 { margin: 0 0 4px; }
+h2 { margin-top: 32px; border-bottom: 1px solid #d0d7de; padding-bottom: 4px; }
+.meta { color: #59636e; }
+table { border-collapse: collapse; width: 100%; background: #fff; }
+th, td { border: 1px solid #d0d7de; padding: 6px 8px; text-align: left; vertical-align: top; }
+th { background: #eef1f4; white-space: nowrap; }
+table.sortable th { cursor: pointer; user-select: none; }
+table.sortable th[data-dir="asc"]::after { content: " ▲"; }
+table.sortable th[data-dir="desc"]::after { content: " ▼"; }
+td.num { text-align: right; font-variant-numeric: tabular-nums; }
+code { font-size: 12px; }
+.kind { display: inline-block; padding: 0 6px; border-radius: 10px; background: #ddf4ff; font-size: 12px; }
+.cards, .charts { display: flex; flex-wrap: wrap; gap: 16px; }
+.card { background: #fff; border: 1px solid #d0d7de; border-radius: 6px; padding: 12px 16px; flex: 1 1 340px; }
+.card h3 { margin: 0 0 8px; font-size: 15px; }
+.option { border-top: 1px solid #eef1f4; padding-top: 8px; margin-top: 8px; }
+.option.newest { border-left: 3px solid #1a7f37; padding-left: 8px; }
+.option ul { margin: 4px 0 0; padding-left: 18px; color: #59636e; }
+svg rect { fill: #0969da; }
+svg .axis { stroke: #d0d7de; }
+svg text { font-size: 12px; fill: #1f2328; }
+svg text.count { fill: #59636e; }
+</style>
+</head>
+<body>
+<main>
+<h1>{{.Title}}</h1>
+<div class="meta">Generated {{.Generated}} &middot; {{.Messages}} messages analyzed &middot; {{.Range}}</div>
+
+<h2>Rules</h2>
+{{if .Rules}}
+<table class="sortable">
+<thead><tr><th>Kind</th><th>Rule</th><th>Category</th><th>Confidence</th><th>Signals</th><th>Sessions</th><th>First seen</th><th>Last seen</th><th>Timeline</th></tr></thead>
+<tbody>
+{{range .Rules}}<tr id="{{.ID}}">
+<td><span class="kind">{{.Kind}}</span></td>
+<td><strong>{{.Key}}</strong><br>{{.Value}}<br><code>{{.ID}}</code></td>
+<td>{{.Category}}</td>
+<td class="num" data-sort="{{printf "%.4f" .Confidence}}">{{printf "%.2f" .Confidence}}</td>
+<td class="num">{{.Signals}}</td>
+<td class="num">{{.Sessions}}</td>
+<td>{{.FirstSeen}}</td>
+<td>{{.LastSeen}}</td>
+<td>{{.Timeline}}</td>
+</tr>
+{{end}}</tbody>
+</table>
+{{else}}<p>No rules were found.</p>{{end}}
+
+{{if .Conflicts}}
+<h2>Conflicts</h2>
+<div class="cards">
+{{range .Conflicts}}<div class="card">
+<h3>{{.Key}}</h3>
+<div class="meta">{{.Category}}</div>
+{{range .Values}}<div class="option{{if .IsNewest}} newest{{end}}">
+<strong>{{.Value}}</strong>{{if .IsNewest}} <span class="kind">most recent</span>{{end}}
+<div class="meta">last seen {{.LastSeen}} &middot; {{.Signals}} signals &middot; confidence {{printf "%.2f" .Strength}}{{if .Members}} &middot; {{.Members}}{{end}}</div>
+{{.Timeline}}
+{{if .Evidence}}<ul>{{range .Evidence}}<li>{{.}}</li>{{end}}</ul>{{end}}
+</div>
+{{end}}</div>
+{{end}}</div>
+{{end}}
+
+{{if .Charts}}
+<h2>Breakdown</h2>
+<div class="charts">
+{{range .Charts}}<div class="card"><h3>{{.Title}}</h3>{{.SVG}}</div>
+{{end}}</div>
+{{end}}
+
+{{if .Stats}}
+<h2>Usage</h2>
+<table class="sortable">
+<thead><tr><th>Project</th><th>Period</th><th>Sessions</th><th>User turns</th><th>Tool calls</th><th>Tool errors</th><th>Corrections / session</th><th>Avg. minutes</th><th>Top commands</th></tr></thead>
+<tbody>
+{{range .Stats}}<tr>
+<td>{{.Project}}</td>
+<td>{{.Period}}</td>
+<td class="num">{{.Sessions}}</td>
+<td class="num">{{.UserTurns}}</td>
+<td class="num">{{.ToolCalls}}</td>
+<td class="num">{{.ToolErrors}}</td>
+<td class="num">{{printf "%.2f" .CorrectionsPerSession}}</td>
+<td class="num">{{printf "%.0f" .AvgSessionMinutes}}</td>
+<td>{{range $i, $c := .Commands}}{{if lt $i 3}}<code>{{$c.Name}}</code> ({{$c.Count}}) {{end}}{{end}}</td>
+</tr>
+{{end}}</tbody>
+</table>
+{{end}}
+</main>
+<script>
+document.querySelectorAll("table.sortable").forEach(function (table) {
+  table.querySelectorAll("th").forEach(function (th, col) {
+    th.addEventListener("click", function () {
+      var dir = th.dataset.dir === "asc" ? "desc" : "asc";
+      table.querySelectorAll("th").forEach(function (h) { delete h.dataset.dir; });
+      th.dataset.dir = dir;
+      var body = table.tBodies[0];
+      var rows = Array.prototype.slice.call(body.rows);
+      var value = function (row) {
+        var cell = row.cells[col];
+        var v = cell.dataset.sort || cell.textContent.trim();
+        return v !== "" && !isNaN(v) ? parseFloat(v) : v.toLowerCase();
+      };
+      rows.sort(function (a, b) {
+        var x = value(a), y = value(b);
+        var c = x < y ? -1 : x > y ? 1 : 0;
+        return dir === "asc" ? c : -c;
+      });
+      rows.forEach(function (row) { body.appendChild(row); });
+    });
+  });
+});
+</script>
+</body>
+</html>
+`))
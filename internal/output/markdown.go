@@ -6,170 +6,316 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"text/template"
+	"time"
 
+	flavorprofile "github.com/strrl/auto-flavor/internal/profile"
 	"github.com/strrl/auto-flavor/internal/signals"
 )
 
+const (
+	ProfileFile = "profile.json"
+	FlavorFile  = "FLAVOR.md"
+)
+
+// Layouts of the flavor directory: one file per rule, a single FLAVOR.md
+// document, or both.
+const (
+	LayoutFiles  = "files"
+	LayoutSingle = "single"
+	LayoutBoth   = "both"
+)
+
+type Config struct {
+	Dir            string `yaml:"dir"`
+	Layout         string `yaml:"layout"`
+	ApplyStrategy  string `yaml:"apply_strategy"`
+	ClaudeFile     string `yaml:"claude_file"`
+	TruncateLength int    `yaml:"truncate_length"`
+	EvidenceCount  int    `yaml:"evidence_count"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Dir:            ".flavor",
+		Layout:         LayoutFiles,
+		ApplyStrategy:  ApplyAuto,
+		ClaudeFile:     claudeFile,
+		TruncateLength: 200,
+		EvidenceCount:  3,
+	}
+}
+
+func (c Config) Validate() error {
+	switch c.Layout {
+	case LayoutFiles, LayoutSingle, LayoutBoth:
+	default:
+		return fmt.Errorf("unknown layout %q (want files, single or both)", c.Layout)
+	}
+
+	switch c.ApplyStrategy {
+	case ApplyAuto, ApplyInline, ApplyImport:
+	default:
+		return fmt.Errorf("unknown apply strategy %q (want auto, inline or import)", c.ApplyStrategy)
+	}
+
+	if base := filepath.Base(c.ClaudeFile); base != claudeFile && base != claudeLocalFile {
+		return fmt.Errorf("claude_file must name a %s or %s, got %q", claudeFile, claudeLocalFile, c.ClaudeFile)
+	}
+	if filepath.IsAbs(c.ClaudeFile) || strings.HasPrefix(filepath.Clean(c.ClaudeFile), "..") {
+		return fmt.Errorf("claude_file must be relative to the project, got %q", c.ClaudeFile)
+	}
+
+	return nil
+}
+
+func (c Config) writesRuleFiles() bool {
+	return c.Layout != LayoutSingle
+}
+
+func (c Config) writesFlavorFile() bool {
+	return c.Layout == LayoutSingle || c.Layout == LayoutBoth || c.ApplyStrategy == ApplyImport
+}
+
+func (c Config) importsFlavorFile() bool {
+	return c.ApplyStrategy == ApplyImport || (c.ApplyStrategy == ApplyAuto && c.writesFlavorFile())
+}
+
 type Generator struct {
 	outputDir string
+	config    Config
+	previous  *signals.FlavorProfile
 }
 
-func NewGenerator(outputDir string) *Generator {
+func NewGenerator(outputDir string, cfg Config) *Generator {
 	return &Generator{
 		outputDir: outputDir,
+		config:    cfg,
 	}
 }
 
 func (g *Generator) Generate(profile *signals.FlavorProfile) ([]string, error) {
-	flavorDir := filepath.Join(g.outputDir, ".flavor")
+	flavorDir := filepath.Join(g.outputDir, g.config.Dir)
 	if err := os.MkdirAll(flavorDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create .flavor directory: %w", err)
+		return nil, fmt.Errorf("failed to create %s directory: %w", g.config.Dir, err)
+	}
+
+	templates, err := g.loadTemplates(flavorDir)
+	if err != nil {
+		return nil, err
 	}
 
 	var files []string
 
-	for _, pref := range profile.StackPreferences {
-		filename, err := g.writePreferenceFile(flavorDir, "stack", pref)
-		if err != nil {
-			return nil, err
+	if g.config.writesRuleFiles() {
+		for _, pref := range profile.StackPreferences {
+			filename, err := g.writePreferenceFile(templates[preferenceTemplate], flavorDir, "stack", pref)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, filename)
 		}
-		files = append(files, filename)
-	}
 
-	for _, pref := range profile.StylePreferences {
-		filename, err := g.writePreferenceFile(flavorDir, "style", pref)
-		if err != nil {
-			return nil, err
+		for _, pref := range profile.StylePreferences {
+			filename, err := g.writePreferenceFile(templates[preferenceTemplate], flavorDir, "style", pref)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, filename)
 		}
-		files = append(files, filename)
-	}
 
-	for _, pref := range profile.Corrections {
-		filename, err := g.writePreferenceFile(flavorDir, "correction", pref)
-		if err != nil {
-			return nil, err
+		for _, pref := range profile.Corrections {
+			filename, err := g.writePreferenceFile(templates[preferenceTemplate], flavorDir, "correction", pref)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, filename)
 		}
-		files = append(files, filename)
-	}
 
-	for _, pref := range profile.Approvals {
-		filename, err := g.writePreferenceFile(flavorDir, "approval", pref)
-		if err != nil {
-			return nil, err
+		for _, pref := range profile.Approvals {
+			filename, err := g.writePreferenceFile(templates[preferenceTemplate], flavorDir, "approval", pref)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, filename)
+		}
+
+		for _, conflict := range profile.Conflicts {
+			filename, err := g.writeConflictFile(templates[conflictTemplate], flavorDir, conflict)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, filename)
+		}
+
+		for _, superseded := range profile.Superseded {
+			filename, err := g.writeSupersededFile(templates[supersededTemplate], flavorDir, superseded)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, filename)
 		}
-		files = append(files, filename)
 	}
 
-	for _, conflict := range profile.Conflicts {
-		filename, err := g.writeConflictFile(flavorDir, conflict)
+	if g.config.writesFlavorFile() {
+		filename, err := g.writeFlavorFile(templates[flavorTemplate], flavorDir, profile)
 		if err != nil {
 			return nil, err
 		}
 		files = append(files, filename)
 	}
 
+	profilePath := filepath.Join(flavorDir, ProfileFile)
+	if previous, err := flavorprofile.Load(profilePath); err == nil {
+		g.previous = previous.Profile
+	}
+	if err := flavorprofile.Save(profilePath, &flavorprofile.Exported{Project: g.outputDir, Profile: profile}); err != nil {
+		return nil, err
+	}
+	files = append(files, profilePath)
+
 	return files, nil
 }
 
-func (g *Generator) writePreferenceFile(flavorDir, prefType string, pref signals.Preference) (string, error) {
-	safeName := sanitizeFilename(pref.Key)
-	filename := filepath.Join(flavorDir, fmt.Sprintf("%s-%s.md", prefType, safeName))
+func (g *Generator) writePreferenceFile(tmpl *template.Template, flavorDir, prefType string, pref signals.Preference) (string, error) {
+	ruleID := RuleID(prefType, pref.Category, pref.Key)
+	filename := filepath.Join(flavorDir, ruleID+".md")
 
-	content := fmt.Sprintf(`# %s: %s
-
-**Category:** %s
-**Confidence:** %.1f
-**Seen:** %d times
-**First seen:** %s
-**Last seen:** %s
-
-## Rule
-
-%s
-`,
-		capitalize(prefType),
-		pref.Key,
-		pref.Category,
-		pref.Confidence,
-		pref.SignalCount,
-		pref.FirstSeen.Format("2006-01-02"),
-		pref.LastSeen.Format("2006-01-02"),
-		pref.Value,
-	)
+	data := PreferenceData{
+		Preference: pref,
+		ID:         ruleID,
+		Kind:       prefType,
+		Evidence:   g.shownEvidence(pref.Evidence),
+	}
 
-	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+	if err := renderFile(tmpl, filename, data); err != nil {
 		return "", fmt.Errorf("failed to write %s file: %w", prefType, err)
 	}
 
 	return filename, nil
 }
 
-func (g *Generator) writeConflictFile(flavorDir string, conflict signals.ConflictingPreference) (string, error) {
+func (g *Generator) shownEvidence(evidence []signals.Evidence) []signals.Evidence {
+	if g.config.EvidenceCount <= 0 {
+		return nil
+	}
+	if len(evidence) > g.config.EvidenceCount {
+		evidence = evidence[:g.config.EvidenceCount]
+	}
+	return evidence
+}
+
+func (g *Generator) writeConflictFile(tmpl *template.Template, flavorDir string, conflict signals.ConflictingPreference) (string, error) {
 	safeName := sanitizeFilename(conflict.Key)
 	filename := filepath.Join(flavorDir, fmt.Sprintf("conflict-%s.undecided.md", safeName))
 
-	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("# Conflict: %s\n\n", conflict.Key))
-	sb.WriteString(fmt.Sprintf("**Category:** %s\n\n", conflict.Category))
-	sb.WriteString("This preference has conflicting signals. Please review and decide which to keep.\n\n")
-
-	for i, v := range conflict.Values {
-		sb.WriteString(fmt.Sprintf("## Option %d", i+1))
-		if i == 0 {
-			sb.WriteString(" (Most Recent)")
-		}
-		sb.WriteString("\n\n")
-		sb.WriteString(fmt.Sprintf("- **Value:** %s\n", truncate(v.Value, 200)))
-		sb.WriteString(fmt.Sprintf("- **Last seen:** %s\n", v.Timestamp.Format("2006-01-02")))
-		sb.WriteString(fmt.Sprintf("- **Signal count:** %d\n", v.SignalCount))
-		sb.WriteString(fmt.Sprintf("- **Strength score:** %.2f\n\n", v.Strength))
+	data := ConflictData{ConflictingPreference: conflict, ID: "conflict-" + safeName}
+	if err := renderFile(tmpl, filename, data); err != nil {
+		return "", fmt.Errorf("failed to write conflict file: %w", err)
 	}
 
-	sb.WriteString("---\n\n")
-	sb.WriteString("## How to Resolve\n\n")
-	sb.WriteString("1. Review each option above\n")
-	sb.WriteString("2. Create a new file with your preferred value\n")
-	sb.WriteString("3. Delete this file when resolved\n")
+	return filename, nil
+}
 
-	if err := os.WriteFile(filename, []byte(sb.String()), 0644); err != nil {
-		return "", fmt.Errorf("failed to write conflict file: %w", err)
+func (g *Generator) writeSupersededFile(tmpl *template.Template, flavorDir string, superseded signals.SupersededPreference) (string, error) {
+	safeName := sanitizeFilename(superseded.Key)
+	filename := filepath.Join(flavorDir, fmt.Sprintf("superseded-%s.md", safeName))
+
+	data := SupersededData{SupersededPreference: superseded, ID: "superseded-" + safeName}
+	if err := renderFile(tmpl, filename, data); err != nil {
+		return "", fmt.Errorf("failed to write superseded file: %w", err)
 	}
 
 	return filename, nil
 }
 
-func (g *Generator) AppendToClaudeMD(profile *signals.FlavorProfile, targetDir string) error {
-	claudeMDPath := filepath.Join(targetDir, "CLAUDE.md")
-
-	var rules []string
+func (g *Generator) writeFlavorFile(tmpl *template.Template, flavorDir string, profile *signals.FlavorProfile) (string, error) {
+	filename := filepath.Join(flavorDir, FlavorFile)
 
-	for _, pref := range profile.StylePreferences {
-		rules = append(rules, fmt.Sprintf("- %s", pref.Value))
+	data := FlavorData{
+		Profile:   profile,
+		Generated: time.Now(),
 	}
 
-	for _, pref := range profile.Corrections {
-		if pref.Category == "prohibition" || pref.Category == "requirement" {
-			rules = append(rules, fmt.Sprintf("- %s", pref.Value))
+	section := func(title, anchor, kind string, prefs []signals.Preference) {
+		s := FlavorSection{Title: title, Anchor: anchor}
+		for _, pref := range prefs {
+			s.Rules = append(s.Rules, PreferenceData{
+				Preference: pref,
+				ID:         RuleID(kind, pref.Category, pref.Key),
+				Kind:       kind,
+				Evidence:   g.shownEvidence(pref.Evidence),
+			})
 		}
+		data.Sections = append(data.Sections, s)
 	}
 
-	if len(rules) == 0 {
-		return nil
+	section("Stack", "stack", "stack", profile.StackPreferences)
+	section("Style", "style", "style", profile.StylePreferences)
+	section("Corrections", "corrections", "correction", profile.Corrections)
+	section("Approvals", "approvals", "approval", profile.Approvals)
+
+	for _, conflict := range profile.Conflicts {
+		data.Conflicts = append(data.Conflicts, ConflictData{ConflictingPreference: conflict, ID: "conflict-" + sanitizeFilename(conflict.Key)})
+	}
+	for _, superseded := range profile.Superseded {
+		data.Superseded = append(data.Superseded, SupersededData{SupersededPreference: superseded, ID: "superseded-" + sanitizeFilename(superseded.Key)})
 	}
 
-	section := fmt.Sprintf("\n\n## Auto-Flavor Rules\n\n%s\n", strings.Join(rules, "\n"))
+	if err := renderFile(tmpl, filename, data); err != nil {
+		return "", fmt.Errorf("failed to write flavor document: %w", err)
+	}
 
-	file, err := os.OpenFile(claudeMDPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return fmt.Errorf("failed to open CLAUDE.md: %w", err)
+	return filename, nil
+}
+
+// ApplyTargets writes the instruction rules of profile into the files of the
+// named assistant targets under targetDir and returns the paths written and
+// warnings about rules left out. Rule scopes are relative to scopeRoot, the
+// repository root, and are rebased onto targetDir. The claude target follows
+// the configured apply strategy and memory file. Sections written to
+// directories by the profile Generate replaced are removed where the
+// directory no longer has rules.
+func (g *Generator) ApplyTargets(profile *signals.FlavorProfile, targetDir, scopeRoot string, names []string) ([]string, []string, error) {
+	var selected []Target
+	for _, name := range names {
+		target, err := LookupTarget(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		selected = append(selected, target)
 	}
-	defer file.Close()
 
-	if _, err := file.WriteString(section); err != nil {
-		return fmt.Errorf("failed to append to CLAUDE.md: %w", err)
+	rules := rebaseRules(InstructionRules(profile), scopeRoot, targetDir)
+
+	var stale []string
+	if g.previous != nil {
+		_, old := splitByDir(rebaseRules(InstructionRules(g.previous), scopeRoot, targetDir))
+		stale = sortedKeys(old)
 	}
 
-	return nil
+	var paths, warnings []string
+	for _, target := range selected {
+		var written, warned []string
+		var err error
+		md, ok := target.(markdownTarget)
+		if ok {
+			md.stale = stale
+			target = md
+		}
+		if ok && md.imports {
+			written, warned, err = g.applyClaude(md, targetDir, rules)
+		} else {
+			written, warned, err = target.Write(targetDir, rules)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		paths = append(paths, written...)
+		warnings = append(warnings, warned...)
+	}
+
+	return paths, warnings, nil
 }
 
 func sanitizeFilename(s string) string {
@@ -193,6 +339,14 @@ func truncate(s string, maxLen int) string {
 	return s
 }
 
+func formatSpan(d time.Duration) string {
+	days := int(d.Hours() / 24)
+	if days == 1 {
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", days)
+}
+
 func capitalize(s string) string {
 	if len(s) == 0 {
 		return s
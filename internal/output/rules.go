@@ -0,0 +1,39 @@
+package output
+
+import (
+	"github.com/strrl/auto-flavor/internal/signals"
+)
+
+// Rule is a preference together with the kind it was categorized as and the
+// stable ID used for its flavor file name.
+type Rule struct {
+	ID         string
+	Kind       string
+	Preference signals.Preference
+}
+
+// RuleID names a rule by its kind, its category where that says more than
+// the kind, and its key: a prohibition and a requirement can share a key.
+func RuleID(kind, category, key string) string {
+	if category != "" && category != kind {
+		kind += "-" + sanitizeFilename(category)
+	}
+	return kind + "-" + sanitizeFilename(key)
+}
+
+func Rules(profile *signals.FlavorProfile) []Rule {
+	var rules []Rule
+
+	add := func(kind string, prefs []signals.Preference) {
+		for _, pref := range prefs {
+			rules = append(rules, Rule{ID: RuleID(kind, pref.Category, pref.Key), Kind: kind, Preference: pref})
+		}
+	}
+
+	add("stack", profile.StackPreferences)
+	add("style", profile.StylePreferences)
+	add("correction", profile.Corrections)
+	add("approval", profile.Approvals)
+
+	return rules
+}
@@ -0,0 +1,66 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/strrl/auto-flavor/internal/stats"
+)
+
+func WriteStatsMarkdown(w io.Writer, reports []stats.Report) {
+	fmt.Fprintln(w, "# Assistant Usage")
+
+	for _, r := range reports {
+		title := r.Project
+		if r.Period != "" {
+			title += " (" + r.Period + ")"
+		}
+		fmt.Fprintf(w, "\n## %s\n\n", title)
+		fmt.Fprintf(w, "%s to %s\n\n", r.Start.Format("2006-01-02"), r.End.Format("2006-01-02"))
+
+		fmt.Fprintln(w, "| Metric | Value |")
+		fmt.Fprintln(w, "|---|---|")
+		fmt.Fprintf(w, "| Sessions | %d |\n", r.Sessions)
+		fmt.Fprintf(w, "| User turns | %d |\n", r.UserTurns)
+		fmt.Fprintf(w, "| Assistant turns | %d |\n", r.AssistantTurns)
+		if r.SubagentTurns > 0 {
+			fmt.Fprintf(w, "| Subagent turns | %d |\n", r.SubagentTurns)
+		}
+		fmt.Fprintf(w, "| Tool calls | %d |\n", r.ToolCalls)
+		fmt.Fprintf(w, "| Tool errors | %d |\n", r.ToolErrors)
+		fmt.Fprintf(w, "| Corrections | %d |\n", r.Corrections)
+		fmt.Fprintf(w, "| Corrections per session | %.2f |\n", r.CorrectionsPerSession)
+		fmt.Fprintf(w, "| Average session length | %.0f min, %.1f turns |\n", r.AvgSessionMinutes, r.AvgTurnsPerSession)
+
+		writeTools(w, "Tools", r.Tools)
+		writeTools(w, "Subagent Tools", r.SubagentTools)
+
+		writeCounts(w, "Most Edited Files", r.Files)
+		writeCounts(w, "Most Run Commands", r.Commands)
+	}
+}
+
+func writeTools(w io.Writer, title string, tools []stats.ToolStat) {
+	if len(tools) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "\n### %s\n\n", title)
+	fmt.Fprintln(w, "| Tool | Calls | Errors | Error rate |")
+	fmt.Fprintln(w, "|---|---|---|---|")
+	for _, t := range tools {
+		fmt.Fprintf(w, "| %s | %d | %d | %.0f%% |\n", t.Name, t.Calls, t.Errors, t.ErrorRate*100)
+	}
+}
+
+func writeCounts(w io.Writer, title string, counts []stats.Count) {
+	if len(counts) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "\n### %s\n\n", title)
+	for i, c := range counts {
+		fmt.Fprintf(w, "%d. `%s` (%d)\n", i+1, strings.ReplaceAll(c.Name, "`", "'"), c.Count)
+	}
+}
@@ -0,0 +1,488 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/strrl/auto-flavor/internal/signals"
+)
+
+const (
+	sectionBegin = "<!-- auto-flavor:begin -->"
+	sectionEnd   = "<!-- auto-flavor:end -->"
+)
+
+// Target renders flavor rules into the instruction files of one coding
+// assistant. Write returns the paths it wrote to and warnings about rules it
+// could not write. It also clears what earlier runs wrote and the current
+// rules no longer need, so it runs even when there are no rules.
+type Target interface {
+	Name() string
+	Write(dir string, rules []InstructionRule) ([]string, []string, error)
+}
+
+var targets = map[string]Target{
+	"claude":   markdownTarget{name: "claude", path: "CLAUDE.md", heading: "## Auto-Flavor Rules", imports: true, nested: true},
+	"agents":   markdownTarget{name: "agents", path: "AGENTS.md", heading: "## Coding Preferences", nested: true},
+	"gemini":   markdownTarget{name: "gemini", path: "GEMINI.md", heading: "## Coding Preferences", nested: true},
+	"copilot":  copilotTarget{},
+	"windsurf": windsurfTarget{},
+	"cursor":   cursorTarget{},
+}
+
+func LookupTarget(name string) (Target, error) {
+	target, ok := targets[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown output target %q (want one of %s)", name, strings.Join(TargetNames(), ", "))
+	}
+	return target, nil
+}
+
+func TargetNames() []string {
+	names := make([]string, 0, len(targets))
+	for name := range targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// InstructionRule is a rule handed to an assistant together with the part
+// of the project it applies to; a nil Scope means the whole project.
+type InstructionRule struct {
+	Text       string
+	Scope      *signals.Scope
+	Confidence float64
+}
+
+// qualified spells the scope out in the rule text, for files that cannot
+// restrict where their rules apply.
+func (r InstructionRule) qualified() string {
+	if r.Scope == nil {
+		return r.Text
+	}
+	return fmt.Sprintf("For `%s`: %s", r.Scope.Glob(), r.Text)
+}
+
+// InstructionRules selects the rules worth handing to an assistant: style
+// preferences and explicit prohibitions or requirements.
+func InstructionRules(profile *signals.FlavorProfile) []InstructionRule {
+	var rules []InstructionRule
+
+	for _, pref := range profile.StylePreferences {
+		rules = append(rules, InstructionRule{Text: pref.Value, Scope: pref.Scope, Confidence: pref.Confidence})
+	}
+
+	for _, pref := range profile.Corrections {
+		if pref.Category == "prohibition" || pref.Category == "requirement" {
+			rules = append(rules, InstructionRule{Text: pref.Value, Scope: pref.Scope, Confidence: pref.Confidence})
+		}
+	}
+
+	return rules
+}
+
+// rebaseRules makes directory scopes, which are relative to root, relative to
+// dir. Rules scoped to a directory outside dir do not apply to anything the
+// files written under dir cover and are left out.
+func rebaseRules(rules []InstructionRule, root, dir string) []InstructionRule {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return rules
+	}
+	prefix := filepath.ToSlash(rel)
+
+	var rebased []InstructionRule
+	for _, rule := range rules {
+		if rule.Scope == nil || rule.Scope.Dir == "" {
+			rebased = append(rebased, rule)
+			continue
+		}
+
+		switch {
+		case rule.Scope.Dir == prefix:
+			rule.Scope = &signals.Scope{Extensions: rule.Scope.Extensions}
+			if len(rule.Scope.Extensions) == 0 {
+				rule.Scope = nil
+			}
+		case strings.HasPrefix(rule.Scope.Dir, prefix+"/"):
+			rule.Scope = &signals.Scope{Dir: strings.TrimPrefix(rule.Scope.Dir, prefix+"/"), Extensions: rule.Scope.Extensions}
+		default:
+			continue
+		}
+		rebased = append(rebased, rule)
+	}
+	return rebased
+}
+
+// splitByDir separates rules scoped to a directory, keyed by it, from the
+// rest. The directory is dropped from the scope of the separated rules since
+// the file they go to already sits in it.
+func splitByDir(rules []InstructionRule) ([]InstructionRule, map[string][]InstructionRule) {
+	var root []InstructionRule
+	nested := make(map[string][]InstructionRule)
+
+	for _, rule := range rules {
+		if rule.Scope == nil || rule.Scope.Dir == "" {
+			root = append(root, rule)
+			continue
+		}
+
+		dir := rule.Scope.Dir
+		if len(rule.Scope.Extensions) > 0 {
+			rule.Scope = &signals.Scope{Extensions: rule.Scope.Extensions}
+		} else {
+			rule.Scope = nil
+		}
+		nested[dir] = append(nested[dir], rule)
+	}
+
+	return root, nested
+}
+
+// splitByGlob separates scoped rules, keyed by the glob of their scope, from
+// the project-wide ones.
+func splitByGlob(rules []InstructionRule) ([]InstructionRule, map[string][]InstructionRule) {
+	var unscoped []InstructionRule
+	scoped := make(map[string][]InstructionRule)
+
+	for _, rule := range rules {
+		if rule.Scope == nil {
+			unscoped = append(unscoped, rule)
+			continue
+		}
+		glob := rule.Scope.Glob()
+		scoped[glob] = append(scoped[glob], InstructionRule{Text: rule.Text})
+	}
+
+	return unscoped, scoped
+}
+
+func sortedKeys(m map[string][]InstructionRule) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// markdownTarget keeps the rules in a marked section of a shared markdown
+// file, replacing the section on every run and leaving the rest untouched.
+// Assistants that read such files from subdirectories get rules scoped to a
+// directory in a file there. Claude Code resolves @path imports, so its file
+// can reference FLAVOR.md instead of listing the rules.
+type markdownTarget struct {
+	name    string
+	path    string
+	heading string
+	imports bool
+	nested  bool
+	stale   []string
+}
+
+func (t markdownTarget) Name() string { return t.name }
+
+func (t markdownTarget) Write(dir string, rules []InstructionRule) ([]string, []string, error) {
+	root, nested := rules, map[string][]InstructionRule(nil)
+	if t.nested {
+		root, nested = splitByDir(rules)
+	}
+
+	var paths []string
+	path := filepath.Join(dir, t.path)
+	if len(root) > 0 {
+		if err := writeManagedSection(path, t.section(root)); err != nil {
+			return nil, nil, err
+		}
+		paths = append(paths, path)
+	} else if err := removeManagedSection(path); err != nil {
+		return nil, nil, err
+	}
+
+	if !t.nested {
+		return paths, nil, nil
+	}
+
+	more, err := t.writeNested(dir, path, nested)
+	if err != nil {
+		return nil, nil, err
+	}
+	return append(paths, more...), nil, nil
+}
+
+// writeNested writes the rules of each directory to the file there and
+// removes the section from the files of the directories the previous run
+// wrote to that no longer have rules. rootFile holds the project-wide rules,
+// which may sit in a subdirectory when the claude file is configured there,
+// and is never cleaned up.
+func (t markdownTarget) writeNested(dir, rootFile string, nested map[string][]InstructionRule) ([]string, error) {
+	var paths []string
+	for _, sub := range sortedKeys(nested) {
+		path := filepath.Join(dir, filepath.FromSlash(sub), filepath.Base(t.path))
+		if err := writeManagedSection(path, t.section(nested[sub])); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+
+	for _, sub := range t.stale {
+		if _, ok := nested[sub]; ok || !filepath.IsLocal(filepath.FromSlash(sub)) {
+			continue
+		}
+		path := filepath.Join(dir, filepath.FromSlash(sub), filepath.Base(t.path))
+		if path == rootFile {
+			continue
+		}
+		if err := removeManagedSection(path); err != nil {
+			return nil, err
+		}
+	}
+
+	return paths, nil
+}
+
+func (t markdownTarget) section(rules []InstructionRule) string {
+	return fmt.Sprintf("%s\n\n%s\n", t.heading, bulletList(rules))
+}
+
+func (t markdownTarget) writeImport(dir, doc string) (string, error) {
+	path := filepath.Join(dir, t.path)
+
+	rel, err := filepath.Rel(filepath.Dir(path), doc)
+	if err != nil {
+		rel = doc
+	}
+
+	section := fmt.Sprintf("%s\n\n@%s\n", t.heading, filepath.ToSlash(rel))
+	return path, writeManagedSection(path, section)
+}
+
+// copilotTarget writes project-wide rules to copilot-instructions.md and
+// scoped rules to path-specific instruction files applied by glob.
+type copilotTarget struct{}
+
+func (copilotTarget) Name() string { return "copilot" }
+
+func (copilotTarget) Write(dir string, rules []InstructionRule) ([]string, []string, error) {
+	unscoped, scoped := splitByGlob(rules)
+
+	var paths []string
+	path := filepath.Join(dir, ".github", "copilot-instructions.md")
+	if len(unscoped) > 0 {
+		if err := writeManagedSection(path, fmt.Sprintf("## Coding Preferences\n\n%s\n", bulletList(unscoped))); err != nil {
+			return nil, nil, err
+		}
+		paths = append(paths, path)
+	} else if err := removeManagedSection(path); err != nil {
+		return nil, nil, err
+	}
+
+	instructionsDir := filepath.Join(dir, ".github", "instructions")
+	var written []string
+	for _, glob := range sortedKeys(scoped) {
+		path := filepath.Join(instructionsDir, "auto-flavor-"+sanitizeFilename(glob)+".instructions.md")
+		content := fmt.Sprintf(`---
+applyTo: %q
+---
+
+# Coding Preferences
+
+%s
+`, glob, bulletList(scoped[glob]))
+
+		if err := writeOwnedFile(path, content); err != nil {
+			return nil, nil, err
+		}
+		written = append(written, path)
+	}
+
+	if err := removeStaleFiles(instructionsDir, "auto-flavor-*.instructions.md", written); err != nil {
+		return nil, nil, err
+	}
+	return append(paths, written...), nil, nil
+}
+
+// windsurfTarget writes plain bullet rules without a heading and keeps the
+// file under Windsurf's rule size limit, filling it with the most confident
+// rules first and leaving out, with a warning, the rules that do not fit.
+type windsurfTarget struct{}
+
+const windsurfLimit = 6000
+
+func (windsurfTarget) Name() string { return "windsurf" }
+
+func (windsurfTarget) Write(dir string, rules []InstructionRule) ([]string, []string, error) {
+	path := filepath.Join(dir, ".windsurfrules")
+
+	existing, err := readOptional(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	budget := windsurfLimit - len(stripManagedSection(existing)) - len(sectionBegin) - len(sectionEnd) - 4
+
+	ranked := append([]InstructionRule(nil), rules...)
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].Confidence > ranked[j].Confidence })
+
+	var kept []InstructionRule
+	for _, rule := range ranked {
+		line := "- " + rule.qualified() + "\n"
+		if budget-len(line) < 0 {
+			continue
+		}
+		budget -= len(line)
+		kept = append(kept, rule)
+	}
+
+	var warnings []string
+	if dropped := len(rules) - len(kept); dropped > 0 {
+		warnings = append(warnings, fmt.Sprintf("%s: left out %d of %d rules to stay under the %d character limit", path, dropped, len(rules), windsurfLimit))
+	}
+
+	if len(kept) == 0 {
+		return nil, warnings, removeManagedSection(path)
+	}
+	return []string{path}, warnings, writeManagedSection(path, bulletList(kept)+"\n")
+}
+
+// cursorTarget owns dedicated project rule files, so they are rewritten as a
+// whole, with the front matter Cursor needs to apply them: always for
+// project-wide rules, by glob for scoped ones.
+type cursorTarget struct{}
+
+func (cursorTarget) Name() string { return "cursor" }
+
+func (cursorTarget) Write(dir string, rules []InstructionRule) ([]string, []string, error) {
+	unscoped, scoped := splitByGlob(rules)
+	rulesDir := filepath.Join(dir, ".cursor", "rules")
+
+	var paths []string
+	if len(unscoped) > 0 {
+		path := filepath.Join(rulesDir, "auto-flavor.mdc")
+		if err := writeOwnedFile(path, cursorRule("", unscoped)); err != nil {
+			return nil, nil, err
+		}
+		paths = append(paths, path)
+	}
+
+	for _, glob := range sortedKeys(scoped) {
+		path := filepath.Join(rulesDir, "auto-flavor-"+sanitizeFilename(glob)+".mdc")
+		if err := writeOwnedFile(path, cursorRule(glob, scoped[glob])); err != nil {
+			return nil, nil, err
+		}
+		paths = append(paths, path)
+	}
+
+	if err := removeStaleFiles(rulesDir, "auto-flavor*.mdc", paths); err != nil {
+		return nil, nil, err
+	}
+	return paths, nil, nil
+}
+
+func cursorRule(glob string, rules []InstructionRule) string {
+	globs := "globs:"
+	if glob != "" {
+		globs += " " + glob
+	}
+
+	return fmt.Sprintf(`---
+description: Coding preferences learned from chat history by auto-flavor
+%s
+alwaysApply: %t
+---
+
+# Coding Preferences
+
+%s
+`, globs, glob == "", bulletList(rules))
+}
+
+func writeOwnedFile(path, content string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// removeStaleFiles deletes the files in dir matching pattern that this run
+// did not write.
+func removeStaleFiles(dir, pattern string, written []string) error {
+	matches, err := filepath.Glob(filepath.Join(dir, pattern))
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+
+	for _, path := range matches {
+		if slices.Contains(written, path) {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+func bulletList(rules []InstructionRule) string {
+	var sb strings.Builder
+	for _, rule := range rules {
+		sb.WriteString("- ")
+		sb.WriteString(strings.ReplaceAll(strings.TrimSpace(rule.qualified()), "\n", " "))
+		sb.WriteString("\n")
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// writeManagedSection replaces the auto-flavor section of path with section,
+// appending it when the file has none yet.
+func writeManagedSection(path, section string) error {
+	existing, err := readOptional(path)
+	if err != nil {
+		return err
+	}
+
+	managed := sectionBegin + "\n" + section + sectionEnd + "\n"
+
+	var content string
+	begin := strings.Index(existing, sectionBegin)
+	end := strings.Index(existing, sectionEnd)
+	switch {
+	case begin >= 0 && end > begin:
+		content = existing[:begin] + managed + strings.TrimPrefix(existing[end+len(sectionEnd):], "\n")
+	case existing == "":
+		content = managed
+	default:
+		content = strings.TrimRight(existing, "\n") + "\n\n" + managed
+	}
+
+	return writeOwnedFile(path, content)
+}
+
+func stripManagedSection(content string) string {
+	begin := strings.Index(content, sectionBegin)
+	end := strings.Index(content, sectionEnd)
+	if begin >= 0 && end > begin {
+		return content[:begin] + content[end+len(sectionEnd):]
+	}
+	return content
+}
+
+func readOptional(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return string(data), nil
+}
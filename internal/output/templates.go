@@ -0,0 +1,316 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/strrl/auto-flavor/internal/signals"
+)
+
+// TemplatesDir is the directory inside the flavor directory where templates
+// overriding the built-in ones are looked up, by the same file names.
+const TemplatesDir = "templates"
+
+const (
+	preferenceTemplate = "preference.md.tmpl"
+	conflictTemplate   = "conflict.md.tmpl"
+	supersededTemplate = "superseded.md.tmpl"
+	flavorTemplate     = "flavor.md.tmpl"
+)
+
+// PreferenceData is what the preference template renders. Evidence holds the
+// excerpts selected for display; the full list stays in Preference.Evidence.
+type PreferenceData struct {
+	signals.Preference
+	ID       string
+	Kind     string
+	Evidence []signals.Evidence
+}
+
+type ConflictData struct {
+	signals.ConflictingPreference
+	ID string
+}
+
+type SupersededData struct {
+	signals.SupersededPreference
+	ID string
+}
+
+// FlavorData is what the consolidated FLAVOR.md template renders. Sections
+// hold the rules of each kind in the order they are listed.
+type FlavorData struct {
+	Profile    *signals.FlavorProfile
+	Generated  time.Time
+	Sections   []FlavorSection
+	Conflicts  []ConflictData
+	Superseded []SupersededData
+}
+
+type FlavorSection struct {
+	Title  string
+	Anchor string
+	Rules  []PreferenceData
+}
+
+var builtinTemplates = map[string]string{
+	preferenceTemplate: `# {{capitalize .Kind}}: {{.Key}}
+
+**ID:** {{.ID}}
+**Category:** {{.Category}}{{with .Scope}}
+**Scope:** ` + "`{{.Glob}}`" + `{{end}}
+**Confidence:** {{printf "%.2f" .Confidence}}
+**Seen:** {{.SignalCount}} times
+**Sessions:** {{.SessionCount}} over {{span .SessionSpan}}
+**First seen:** {{date .FirstSeen}}
+**Last seen:** {{date .LastSeen}}
+
+## Rule
+
+{{.Value}}
+
+## Confidence Breakdown
+
+- **Recency:** {{printf "%.2f" .Breakdown.Recency}}
+- **Frequency:** {{printf "%.2f" .Breakdown.Frequency}}
+- **Strength:** {{printf "%.2f" .Breakdown.Strength}}
+- **Session spread:** {{printf "%.2f" .Breakdown.SessionSpread}}
+{{- if gt (len .Projects) 1}}
+
+## Projects
+{{range .Projects}}
+- {{.}}
+{{- end}}
+{{- end}}
+{{- if .Members}}
+
+## Members
+{{range .Members}}
+- {{.}}
+{{- end}}
+{{- end}}
+{{- if .Evidence}}
+
+## Evidence
+{{range $i, $e := .Evidence}}
+{{inc $i}}. {{date $e.Timestamp}}: "{{truncate $e.Text}}"
+{{- if $e.Context}}
+   - In reply to: {{truncate $e.Context}}
+{{- end}}
+{{- if $e.SessionID}}
+   - Session: ` + "`{{$e.SessionID}}`, message: `{{$e.MessageUUID}}`" + `
+{{- end}}
+{{- if $e.SourceFile}}
+   - Source: ` + "`{{$e.SourceFile}}:{{$e.SourceLine}}`" + `
+{{- end}}
+{{- end}}
+{{- end}}
+`,
+
+	conflictTemplate: `# Conflict: {{.Key}}
+
+**Category:** {{.Category}}
+
+This preference has conflicting signals. Please review and decide which to keep.
+{{range $i, $v := .Values}}
+## Option {{inc $i}}{{if eq $i 0}} (Most Recent){{end}}
+
+- **Value:** {{truncate $v.Value}}
+- **Last seen:** {{date $v.Timestamp}}
+- **Signal count:** {{$v.SignalCount}}
+{{- if $v.Members}}
+- **Members:** {{join $v.Members ", "}}
+{{- end}}
+- **Confidence:** {{printf "%.2f" $v.Strength}}
+{{end}}
+---
+
+## How to Resolve
+
+1. Review each option above
+2. Create a new file with your preferred value
+3. Delete this file when resolved
+`,
+
+	supersededTemplate: `# Superseded: {{.Key}}
+
+**Category:** {{.Category}}
+**Switched on:** {{date .SwitchedAt}}
+
+This preference changed over time. The new value has consistently replaced the old one.
+
+## Current
+
+- **Value:** {{truncate .NewValue}}
+- **Last seen:** {{date .NewLastSeen}}
+- **Signal count:** {{.NewCount}}
+
+## Previous
+
+- **Value:** {{truncate .OldValue}}
+- **Last seen:** {{date .OldLastSeen}}
+- **Signal count:** {{.OldCount}}
+`,
+
+	flavorTemplate: `# Coding Flavor
+
+Coding preferences learned from chat history by auto-flavor, last generated
+on {{date .Generated}}. The badge next to each rule shows how well the
+history supports it.
+
+## Contents
+{{range .Sections}}{{if .Rules}}
+- [{{.Title}}](#{{.Anchor}})
+{{- range .Rules}}
+  - [{{.Key}}](#{{.ID}})
+{{- end}}
+{{- end}}{{end}}
+{{- if .Conflicts}}
+- [Conflicts](#conflicts)
+{{- end}}
+{{- if .Superseded}}
+- [Superseded](#superseded)
+{{- end}}
+{{range .Sections}}{{if .Rules}}
+## {{.Title}}
+{{range .Rules}}
+### <a id="{{.ID}}"></a>{{.Key}}
+
+{{badge .Confidence}} ` + "`{{.Category}}`" + `{{with .Scope}} · applies to ` + "`{{.Glob}}`" + `{{end}} · seen {{.SignalCount}} times in {{.SessionCount}} sessions, last on {{date .LastSeen}}
+
+{{.Value}}
+{{- if .Evidence}}
+
+<details>
+<summary>Evidence ({{len .Evidence}})</summary>
+{{range $i, $e := .Evidence}}
+{{inc $i}}. {{date $e.Timestamp}}: "{{truncate $e.Text}}"
+{{- if $e.Context}}
+   - In reply to: {{truncate $e.Context}}
+{{- end}}
+{{- end}}
+
+</details>
+{{- end}}
+{{end}}{{end}}{{end}}
+{{- if .Conflicts}}
+## Conflicts
+
+These preferences have conflicting signals and are not applied until one
+value is chosen.
+{{range .Conflicts}}
+### <a id="{{.ID}}"></a>{{.Key}}
+{{range $i, $v := .Values}}
+- {{badge $v.Strength}} {{truncate $v.Value}} ({{$v.SignalCount}} signals, last on {{date $v.Timestamp}}
+{{- if $v.Members}}, held by {{join $v.Members ", "}}{{end}})
+{{- end}}
+{{end}}{{end}}
+{{- if .Superseded}}
+## Superseded
+{{range .Superseded}}
+- **{{.Key}}:** {{truncate .NewValue}} replaced {{truncate .OldValue}} on {{date .SwitchedAt}}
+{{- end}}
+{{end}}`,
+}
+
+// WriteBuiltinTemplates copies the built-in templates into the templates
+// directory as a starting point for overrides. Existing files are kept unless
+// force is set.
+func WriteBuiltinTemplates(flavorDir string, force bool) ([]string, error) {
+	dir := filepath.Join(flavorDir, TemplatesDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create templates directory: %w", err)
+	}
+
+	var written []string
+	for _, name := range TemplateNames() {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil && !force {
+			continue
+		}
+		if err := os.WriteFile(path, []byte(builtinTemplates[name]), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		written = append(written, path)
+	}
+
+	return written, nil
+}
+
+func TemplateNames() []string {
+	return []string{preferenceTemplate, conflictTemplate, supersededTemplate, flavorTemplate}
+}
+
+func (g *Generator) templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"date": func(t time.Time) string { return t.Format("2006-01-02") },
+		"span": formatSpan,
+		"truncate": func(s string, maxLen ...int) string {
+			if len(maxLen) > 0 {
+				return truncate(s, maxLen[0])
+			}
+			return truncate(s, g.config.TruncateLength)
+		},
+		"capitalize": capitalize,
+		"join":       strings.Join,
+		"inc":        func(i int) int { return i + 1 },
+		"badge":      confidenceBadge,
+	}
+}
+
+// confidenceBadge renders a confidence as a colored marker with its value,
+// e.g. "🟢 0.82".
+func confidenceBadge(confidence float64) string {
+	marker := "🔴"
+	switch {
+	case confidence >= 0.7:
+		marker = "🟢"
+	case confidence >= 0.4:
+		marker = "🟡"
+	}
+	return fmt.Sprintf("%s %.2f", marker, confidence)
+}
+
+// loadTemplates parses the built-in templates, replacing each one that has
+// a file of the same name in the templates directory.
+func (g *Generator) loadTemplates(flavorDir string) (map[string]*template.Template, error) {
+	templates := make(map[string]*template.Template)
+
+	for _, name := range TemplateNames() {
+		text := builtinTemplates[name]
+
+		path := filepath.Join(flavorDir, TemplatesDir, name)
+		data, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read template %s: %w", path, err)
+		}
+		if err == nil {
+			text = string(data)
+		}
+
+		tmpl, err := template.New(name).Funcs(g.templateFuncs()).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template %s: %w", name, err)
+		}
+		templates[name] = tmpl
+	}
+
+	return templates, nil
+}
+
+func renderFile(tmpl *template.Template, filename string, data any) error {
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return fmt.Errorf("failed to render %s: %w", tmpl.Name(), err)
+	}
+
+	if err := os.WriteFile(filename, []byte(sb.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+
+	return nil
+}
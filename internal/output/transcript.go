@@ -0,0 +1,77 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/strrl/auto-flavor/internal/parser"
+)
+
+type TranscriptOptions struct {
+	Highlight   map[string]string
+	TruncateLen int
+}
+
+// WriteTranscript renders parsed entries as a readable conversation. Entries
+// whose UUID is in opts.Highlight are marked with the associated note.
+func WriteTranscript(w io.Writer, entries []*parser.ParsedEntry, opts TranscriptOptions) {
+	for _, entry := range entries {
+		marker := "  "
+		if _, ok := opts.Highlight[entry.UUID]; ok {
+			marker = "> "
+		}
+
+		fmt.Fprintf(w, "%s[%s] %s", marker, entry.Timestamp.Format("2006-01-02 15:04:05"), entry.Type)
+		if entry.SourceLine > 0 {
+			fmt.Fprintf(w, " (line %d)", entry.SourceLine)
+		}
+		fmt.Fprintln(w)
+
+		if note, ok := opts.Highlight[entry.UUID]; ok && note != "" {
+			fmt.Fprintf(w, "    ** %s\n", note)
+		}
+
+		switch entry.Type {
+		case "user":
+			if entry.UserContent != "" {
+				writeIndented(w, clip(entry.UserContent, opts.TruncateLen))
+			}
+			for _, result := range entry.GetToolResults() {
+				label := "<- result"
+				if result.IsError {
+					label = "<- error"
+				}
+				writeIndented(w, fmt.Sprintf("%s %s", label, clip(result.ResultText(), opts.TruncateLen)))
+			}
+		case "assistant":
+			for _, block := range entry.AssistantContent {
+				switch block.Type {
+				case "text":
+					writeIndented(w, clip(block.Text, opts.TruncateLen))
+				case "tool_use":
+					writeIndented(w, fmt.Sprintf("-> %s %s", block.Name, clip(string(block.Input), opts.TruncateLen)))
+				}
+			}
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+func writeIndented(w io.Writer, text string) {
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		fmt.Fprintf(w, "    %s\n", line)
+	}
+}
+
+func clip(s string, maxLen int) string {
+	if maxLen <= 0 || len(s) <= maxLen {
+		return s
+	}
+	cut := maxLen
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "..."
+}
@@ -0,0 +1,181 @@
+package parser
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const aiderHistoryFile = ".aider.chat.history.md"
+
+// aiderSource reads the .aider.chat.history.md that Aider keeps in the root
+// of each repository it runs in. Aider has no central history, so the files
+// are looked up in the filtered project or in every directory known from the
+// Claude Code history.
+type aiderSource struct {
+	parser *Parser
+}
+
+func (s aiderSource) Name() string { return "aider" }
+
+func (s aiderSource) Fetch(filter SourceFilter) ([]*ParsedEntry, error) {
+	var dirs []string
+	if filter.Project != "" {
+		target := ResolveProject(filter.Project)
+		dirs = append(dirs, target.Path)
+		if target.Root() != target.Path {
+			dirs = append(dirs, target.Root())
+		}
+	} else {
+		projects, err := s.parser.ListProjects()
+		if err != nil {
+			return nil, err
+		}
+		dirs = projects
+	}
+
+	seen := make(map[string]struct{})
+	var entries []*ParsedEntry
+
+	for _, dir := range dirs {
+		path := filepath.Join(dir, aiderHistoryFile)
+		if _, ok := seen[path]; ok {
+			continue
+		}
+		seen[path] = struct{}{}
+
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+
+		history, err := readAiderHistory(path)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range history {
+			if filter.Since.IsZero() || !entry.Timestamp.Before(filter.Since) {
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	return entries, nil
+}
+
+// readAiderHistory splits the markdown log into sessions, which start with
+// "# aider chat started at", user messages, whose lines are prefixed with
+// "#### ", tool output, prefixed with "> ", and assistant replies in between.
+// Only session starts carry a time, so messages are spaced a second apart.
+func readAiderHistory(path string) ([]*ParsedEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	cwd := filepath.Dir(path)
+
+	var (
+		entries   []*ParsedEntry
+		current   *ParsedEntry
+		text      strings.Builder
+		sessionID string
+		start     time.Time
+		seq       int
+	)
+
+	flush := func() {
+		if current == nil {
+			return
+		}
+		content := strings.TrimSpace(text.String())
+		if current.Type == "user" {
+			current.UserContent = content
+		} else if content != "" {
+			current.AssistantContent = append([]ContentBlock{{Type: "text", Text: content}}, current.AssistantContent...)
+		}
+		if content != "" || len(current.AssistantContent) > 0 {
+			if len(entries) > 0 && entries[len(entries)-1].SessionID == current.SessionID {
+				current.ParentUUID = entries[len(entries)-1].UUID
+			}
+			entries = append(entries, current)
+		}
+		current = nil
+		text.Reset()
+	}
+
+	begin := func(entryType string, lineNo int) {
+		flush()
+		seq++
+		current = &ParsedEntry{
+			Type:       entryType,
+			Timestamp:  start.Add(time.Duration(seq) * time.Second),
+			SessionID:  sessionID,
+			UUID:       fmt.Sprintf("%s:%d", sessionID, lineNo),
+			CWD:        cwd,
+			SourceFile: path,
+			SourceLine: lineNo,
+		}
+	}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+
+		switch {
+		case strings.HasPrefix(line, "# aider chat started at "):
+			flush()
+			ts, err := time.ParseInLocation("2006-01-02 15:04:05", strings.TrimPrefix(line, "# aider chat started at "), time.Local)
+			if err != nil {
+				continue
+			}
+			start, seq = ts, 0
+			sessionID = fmt.Sprintf("aider-%s", ts.Format("20060102T150405"))
+		case sessionID == "":
+			continue
+		case strings.HasPrefix(line, "#### ") || line == "####":
+			if current == nil || current.Type != "user" {
+				begin("user", lineNo)
+			}
+			text.WriteString(strings.TrimPrefix(line, "#### "))
+			text.WriteString("\n")
+		case strings.HasPrefix(line, "> "):
+			if current != nil && current.Type == "user" {
+				flush()
+			}
+			if file, ok := strings.CutPrefix(line, "> Applied edit to "); ok {
+				if current == nil || current.Type != "assistant" {
+					begin("assistant", lineNo)
+				}
+				input, _ := json.Marshal(EditToolInput{FilePath: filepath.Join(cwd, strings.TrimSpace(file))})
+				current.AssistantContent = append(current.AssistantContent, ContentBlock{Type: "tool_use", Name: "Edit", Input: input})
+			}
+		default:
+			if current != nil && current.Type == "user" {
+				flush()
+			}
+			if current == nil {
+				if strings.TrimSpace(line) == "" {
+					continue
+				}
+				begin("assistant", lineNo)
+			}
+			text.WriteString(line)
+			text.WriteString("\n")
+		}
+	}
+	flush()
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	return entries, nil
+}
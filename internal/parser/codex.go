@@ -0,0 +1,281 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// codexSource reads Codex CLI rollout files, ~/.codex/sessions/**/rollout-*.jsonl.
+// Current rollouts wrap every record in {"timestamp", "type", "payload"} and
+// open with a session_meta record; older ones start with a bare session header
+// followed by bare response items.
+type codexSource struct {
+	dir string
+}
+
+func (s codexSource) Name() string { return "codex" }
+
+func (s codexSource) Fetch(filter SourceFilter) ([]*ParsedEntry, error) {
+	if _, err := os.Stat(s.dir); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	matches := filter.projectMatcher()
+	var entries []*ParsedEntry
+
+	err := filepath.WalkDir(s.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasPrefix(d.Name(), "rollout-") || filepath.Ext(path) != ".jsonl" {
+			return nil
+		}
+
+		if !filter.Since.IsZero() {
+			if info, err := d.Info(); err == nil && info.ModTime().Before(filter.Since) {
+				return nil
+			}
+		}
+
+		rollout, err := readCodexRollout(path)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range rollout {
+			if filter.keep(entry, matches) {
+				entries = append(entries, entry)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read codex sessions: %w", err)
+	}
+
+	return entries, nil
+}
+
+type codexRecord struct {
+	Timestamp time.Time       `json:"timestamp"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+type codexItem struct {
+	Type      string          `json:"type"`
+	Role      string          `json:"role"`
+	Content   []codexContent  `json:"content"`
+	Name      string          `json:"name"`
+	Arguments string          `json:"arguments"`
+	Input     string          `json:"input"`
+	CallID    string          `json:"call_id"`
+	Output    json.RawMessage `json:"output"`
+
+	// Session metadata, from session_meta payloads or old-style headers.
+	ID  string `json:"id"`
+	CWD string `json:"cwd"`
+}
+
+type codexContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+var codexCWD = regexp.MustCompile(`<cwd>([^<]+)</cwd>`)
+
+func readCodexRollout(path string) ([]*ParsedEntry, error) {
+	var (
+		entries   []*ParsedEntry
+		sessionID = strings.TrimSuffix(filepath.Base(path), ".jsonl")
+		cwd       string
+		lastTime  time.Time
+	)
+
+	err := scanJSONL(path, func(lineNo int, line []byte) bool {
+		var record codexRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			return true
+		}
+
+		var item codexItem
+		if len(record.Payload) > 0 {
+			if err := json.Unmarshal(record.Payload, &item); err != nil {
+				return true
+			}
+		} else if err := json.Unmarshal(line, &item); err != nil {
+			return true
+		}
+
+		if !record.Timestamp.IsZero() {
+			lastTime = record.Timestamp
+		}
+
+		switch record.Type {
+		case "session_meta", "turn_context":
+			if item.ID != "" {
+				sessionID = item.ID
+			}
+			if item.CWD != "" {
+				cwd = item.CWD
+			}
+			return true
+		case "", "response_item":
+		default:
+			return true
+		}
+
+		if record.Type == "" && item.Type == "" && item.ID != "" {
+			sessionID = item.ID
+			return true
+		}
+
+		entry := &ParsedEntry{
+			Timestamp:  lastTime,
+			SessionID:  sessionID,
+			UUID:       fmt.Sprintf("%s:%d", sessionID, lineNo),
+			SourceFile: path,
+			SourceLine: lineNo,
+		}
+
+		switch item.Type {
+		case "message":
+			text := codexText(item.Content)
+			if item.Role == "user" {
+				if m := codexCWD.FindStringSubmatch(text); m != nil && cwd == "" {
+					cwd = strings.TrimSpace(m[1])
+				}
+				if isCodexContext(text) {
+					return true
+				}
+				entry.Type = "user"
+				entry.UserContent = text
+			} else if item.Role == "assistant" {
+				entry.Type = "assistant"
+				entry.AssistantContent = []ContentBlock{{Type: "text", Text: text}}
+			} else {
+				return true
+			}
+		case "function_call", "local_shell_call", "custom_tool_call":
+			entry.Type = "assistant"
+			entry.AssistantContent = codexToolUses(item)
+		case "function_call_output", "custom_tool_call_output":
+			entry.Type = "user"
+			entry.UserBlocks = []ContentBlock{codexToolResult(item)}
+		default:
+			return true
+		}
+
+		if len(entries) > 0 {
+			entry.ParentUUID = entries[len(entries)-1].UUID
+		}
+		entries = append(entries, entry)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		entry.CWD = cwd
+		entry.SessionID = sessionID
+	}
+
+	return entries, nil
+}
+
+func codexText(content []codexContent) string {
+	var parts []string
+	for _, c := range content {
+		if c.Text != "" {
+			parts = append(parts, c.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// isCodexContext recognizes the user messages Codex injects itself to pass
+// the environment and AGENTS.md instructions to the model.
+func isCodexContext(text string) bool {
+	text = strings.TrimSpace(text)
+	return strings.HasPrefix(text, "<environment_context>") || strings.HasPrefix(text, "<user_instructions>")
+}
+
+var codexPatchFile = regexp.MustCompile(`(?m)^\*\*\* (Add|Update) File: (.+)$`)
+
+// codexToolUses maps Codex tool calls onto the Claude Code tools the detector
+// knows: shell commands become Bash and patches become Edit or Write.
+func codexToolUses(item codexItem) []ContentBlock {
+	switch item.Name {
+	case "shell", "container.exec", "local_shell":
+		var args struct {
+			Command []string `json:"command"`
+		}
+		if err := json.Unmarshal([]byte(item.Arguments), &args); err == nil && len(args.Command) > 0 {
+			command := strings.Join(args.Command, " ")
+			if len(args.Command) == 3 && (args.Command[1] == "-lc" || args.Command[1] == "-c") {
+				command = args.Command[2]
+			}
+			input, _ := json.Marshal(BashToolInput{Command: command})
+			return []ContentBlock{{Type: "tool_use", ID: item.CallID, Name: "Bash", Input: input}}
+		}
+	case "apply_patch":
+		patch := item.Input
+		if patch == "" {
+			var args struct {
+				Input string `json:"input"`
+			}
+			if json.Unmarshal([]byte(item.Arguments), &args) == nil {
+				patch = args.Input
+			}
+		}
+
+		var blocks []ContentBlock
+		for _, m := range codexPatchFile.FindAllStringSubmatch(patch, -1) {
+			name := "Edit"
+			if m[1] == "Add" {
+				name = "Write"
+			}
+			input, _ := json.Marshal(EditToolInput{FilePath: strings.TrimSpace(m[2])})
+			blocks = append(blocks, ContentBlock{Type: "tool_use", ID: item.CallID, Name: name, Input: input})
+		}
+		if len(blocks) > 0 {
+			return blocks
+		}
+	}
+
+	input := json.RawMessage(item.Arguments)
+	if !json.Valid(input) {
+		input, _ = json.Marshal(map[string]string{"input": item.Arguments + item.Input})
+	}
+	return []ContentBlock{{Type: "tool_use", ID: item.CallID, Name: item.Name, Input: input}}
+}
+
+func codexToolResult(item codexItem) ContentBlock {
+	result := ContentBlock{Type: "tool_result", ToolUseID: item.CallID}
+
+	var output string
+	if err := json.Unmarshal(item.Output, &output); err != nil {
+		output = string(item.Output)
+	}
+
+	var structured struct {
+		Output   string `json:"output"`
+		Metadata struct {
+			ExitCode int `json:"exit_code"`
+		} `json:"metadata"`
+	}
+	if json.Unmarshal([]byte(output), &structured) == nil && structured.Output != "" {
+		output = structured.Output
+		result.IsError = structured.Metadata.ExitCode != 0
+	}
+
+	result.Content, _ = json.Marshal(output)
+	return result
+}
@@ -0,0 +1,248 @@
+package parser
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// exportSource reads chats exported from editors: VS Code "Export Chat" JSON
+// files and Cursor markdown exports. Exports carry no working directory, so
+// each one is attributed to the directory it is stored in; keeping exports
+// inside a repository ties them to that project.
+type exportSource struct {
+	dirs []string
+}
+
+func (s exportSource) Name() string { return "export" }
+
+func (s exportSource) Fetch(filter SourceFilter) ([]*ParsedEntry, error) {
+	matches := filter.projectMatcher()
+	var entries []*ParsedEntry
+
+	for _, dir := range s.dirs {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			continue
+		}
+
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+
+			var chat []*ParsedEntry
+			switch filepath.Ext(path) {
+			case ".json":
+				chat, err = readVSCodeExport(path)
+			case ".md":
+				chat, err = readCursorExport(path)
+			default:
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			for _, entry := range chat {
+				if filter.keep(entry, matches) {
+					entries = append(entries, entry)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read chat exports in %s: %w", dir, err)
+		}
+	}
+
+	return entries, nil
+}
+
+type vscodeExport struct {
+	Requests []struct {
+		Message struct {
+			Text string `json:"text"`
+		} `json:"message"`
+		Response []struct {
+			Value string `json:"value"`
+			Kind  string `json:"kind"`
+		} `json:"response"`
+		Timestamp int64 `json:"timestamp"`
+	} `json:"requests"`
+}
+
+// readVSCodeExport parses a chat exported from VS Code. Files that are not
+// chat exports yield no entries.
+func readVSCodeExport(path string) ([]*ParsedEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var export vscodeExport
+	if err := json.Unmarshal(data, &export); err != nil || len(export.Requests) == 0 {
+		return nil, nil
+	}
+
+	base := fileTime(path)
+	chat := newExportChat(path)
+
+	for i, req := range export.Requests {
+		ts := base.Add(time.Duration(i-len(export.Requests)) * time.Minute)
+		if req.Timestamp > 0 {
+			ts = time.UnixMilli(req.Timestamp)
+		}
+
+		chat.add("user", req.Message.Text, ts, 0)
+
+		var parts []string
+		for _, r := range req.Response {
+			if r.Value != "" && (r.Kind == "" || r.Kind == "markdownContent") {
+				parts = append(parts, r.Value)
+			}
+		}
+		chat.add("assistant", strings.Join(parts, ""), ts.Add(time.Second), 0)
+	}
+
+	return chat.entries, nil
+}
+
+// readCursorExport parses a chat exported from Cursor as markdown, where
+// turns are separated by "---" and open with a **User** or **Cursor** line.
+// Other markdown files yield no entries.
+func readCursorExport(path string) ([]*ParsedEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	type turn struct {
+		role string
+		line int
+		text strings.Builder
+	}
+
+	var (
+		turns    []*turn
+		current  *turn
+		exported time.Time
+		isCursor bool
+	)
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(trimmed, "_Exported on ") && strings.Contains(trimmed, "from Cursor"):
+			isCursor = true
+			exported = parseCursorExportTime(trimmed)
+		case trimmed == "**User**":
+			current = &turn{role: "user", line: lineNo}
+			turns = append(turns, current)
+		case trimmed == "**Cursor**":
+			current = &turn{role: "assistant", line: lineNo}
+			turns = append(turns, current)
+		case trimmed == "---":
+			current = nil
+		case current != nil:
+			current.text.WriteString(line)
+			current.text.WriteString("\n")
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	if !isCursor {
+		return nil, nil
+	}
+
+	if exported.IsZero() {
+		exported = fileTime(path)
+	}
+
+	chat := newExportChat(path)
+	for i, t := range turns {
+		chat.add(t.role, t.text.String(), exported.Add(time.Duration(i-len(turns))*time.Second), t.line)
+	}
+
+	return chat.entries, nil
+}
+
+// parseCursorExportTime reads the date and time from a line such as
+// "_Exported on 5/1/2025 at 10:00:00 GMT+2 from Cursor (0.49.6)_". The zone
+// is written as an offset name Go cannot parse, so local time is assumed.
+func parseCursorExportTime(line string) time.Time {
+	fields := strings.Fields(strings.TrimPrefix(strings.Trim(line, "_"), "Exported on "))
+	if len(fields) < 3 || fields[1] != "at" {
+		return time.Time{}
+	}
+
+	ts, err := time.ParseInLocation("1/2/2006 15:04:05", fields[0]+" "+fields[2], time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+	return ts
+}
+
+type exportChat struct {
+	path      string
+	sessionID string
+	entries   []*ParsedEntry
+}
+
+func newExportChat(path string) *exportChat {
+	return &exportChat{
+		path:      path,
+		sessionID: "export-" + strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+	}
+}
+
+func (c *exportChat) add(role, text string, ts time.Time, line int) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+
+	entry := &ParsedEntry{
+		Type:       role,
+		Timestamp:  ts,
+		SessionID:  c.sessionID,
+		UUID:       fmt.Sprintf("%s:%d", c.sessionID, len(c.entries)+1),
+		CWD:        filepath.Dir(c.path),
+		SourceFile: c.path,
+		SourceLine: line,
+	}
+
+	if role == "user" {
+		entry.UserContent = text
+	} else {
+		entry.AssistantContent = []ContentBlock{{Type: "text", Text: text}}
+	}
+
+	if len(c.entries) > 0 {
+		entry.ParentUUID = c.entries[len(c.entries)-1].UUID
+	}
+	c.entries = append(c.entries, entry)
+}
+
+func fileTime(path string) time.Time {
+	if info, err := os.Stat(path); err == nil {
+		return info.ModTime()
+	}
+	return time.Now()
+}
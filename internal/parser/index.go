@@ -0,0 +1,191 @@
+package parser
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const indexVersion = 1
+
+// historyIndex records the words in every history file, so that a search only
+// reads the files that can hold a match. It is kept in the user cache
+// directory and only files whose size or modification time changed since the
+// last search are indexed again.
+type historyIndex struct {
+	Version   int                    `json:"version"`
+	ClaudeDir string                 `json:"claude_dir"`
+	Files     map[string]indexedFile `json:"files"`
+}
+
+type indexedFile struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Words   []string  `json:"words"`
+}
+
+func indexPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get cache directory: %w", err)
+	}
+	return filepath.Join(dir, "auto-flavor", "search-index.json"), nil
+}
+
+// loadIndex reads the stored index and brings it up to date with the history
+// files.
+func (p *Parser) loadIndex() (*historyIndex, error) {
+	path, err := indexPath()
+	if err != nil {
+		return nil, err
+	}
+
+	index := &historyIndex{}
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, index)
+	}
+	if index.Version != indexVersion || index.ClaudeDir != p.claudeDir || index.Files == nil {
+		index = &historyIndex{Version: indexVersion, ClaudeDir: p.claudeDir, Files: make(map[string]indexedFile)}
+	}
+
+	changed := false
+	seen := make(map[string]bool)
+
+	err = filepath.WalkDir(p.claudeDir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || filepath.Ext(file) != ".jsonl" {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		seen[file] = true
+
+		cached, ok := index.Files[file]
+		if ok && cached.Size == info.Size() && cached.ModTime.Equal(info.ModTime()) {
+			return nil
+		}
+
+		words, err := fileWords(file)
+		if err != nil {
+			return fmt.Errorf("failed to index %s: %w", file, err)
+		}
+		index.Files[file] = indexedFile{Size: info.Size(), ModTime: info.ModTime(), Words: words}
+		changed = true
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to index history: %w", err)
+	}
+
+	for file := range index.Files {
+		if !seen[file] {
+			delete(index.Files, file)
+			changed = true
+		}
+	}
+
+	if changed {
+		// An index that cannot be stored is still good for this search.
+		_ = index.save(path)
+	}
+
+	return index, nil
+}
+
+func (idx *historyIndex) save(path string) error {
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// candidates lists the files holding every word of every term. Terms match
+// inside words, the way the ILIKE check on the messages does, so the index
+// never rules out a file that would match.
+func (idx *historyIndex) candidates(terms []string) []string {
+	var parts []string
+	for _, term := range terms {
+		parts = append(parts, splitWords(strings.ToLower(term))...)
+	}
+
+	var files []string
+	for file, indexed := range idx.Files {
+		if containsAll(indexed.Words, parts) {
+			files = append(files, file)
+		}
+	}
+
+	slices.Sort(files)
+	return files
+}
+
+func containsAll(words, parts []string) bool {
+	for _, part := range parts {
+		if !slices.ContainsFunc(words, func(word string) bool { return strings.Contains(word, part) }) {
+			return false
+		}
+	}
+	return true
+}
+
+func fileWords(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	set := make(map[string]struct{})
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadString('\n')
+		for _, word := range splitWords(strings.ToLower(line)) {
+			set[word] = struct{}{}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	words := make([]string, 0, len(set))
+	for word := range set {
+		words = append(words, word)
+	}
+	slices.Sort(words)
+	return words, nil
+}
+
+func splitWords(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
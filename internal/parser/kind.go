@@ -0,0 +1,139 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// EntryKind tells what a user entry holds. Histories record more than what
+// the user typed as user entries: slash command invocations, the output of
+// local commands, hook messages and text the agent injected itself.
+type EntryKind string
+
+const (
+	KindPrompt        EntryKind = "prompt"
+	KindSlashCommand  EntryKind = "slash_command"
+	KindCommandOutput EntryKind = "command_output"
+	KindHook          EntryKind = "hook"
+	KindSystem        EntryKind = "system"
+)
+
+func EntryKinds() []EntryKind {
+	return []EntryKind{KindPrompt, KindSlashCommand, KindCommandOutput, KindHook, KindSystem}
+}
+
+var (
+	commandNameTag = regexp.MustCompile(`<command-name>\s*(/?[^<\s]+)\s*</command-name>`)
+	commandArgsTag = regexp.MustCompile(`(?s)<command-args>(.*?)</command-args>`)
+	typedCommand   = regexp.MustCompile(`^(/[a-z][\w:-]*)(?:\s|$)`)
+
+	commandOutputTag = regexp.MustCompile(`^<(local-command-stdout|local-command-stderr|bash-input|bash-stdout|bash-stderr)>`)
+
+	hookMessage = regexp.MustCompile(`^(PreToolUse|PostToolUse|UserPromptSubmit|Notification|Stop|SubagentStop|PreCompact|SessionStart|SessionEnd)(?::(\S+))?\s+(?:hook|\[)`)
+	hookTag     = regexp.MustCompile(`^<([a-z-]+-hook)>`)
+
+	// builtinCommands are the slash commands of Claude Code and Codex. A
+	// typed /name is taken for a command only if it is one of them, is
+	// namespaced like /prompts:name, or was invoked as a command elsewhere in
+	// the history, so that "/etc is broken" stays a prompt.
+	builtinCommands = map[string]bool{
+		"add-dir": true, "agents": true, "approvals": true, "bug": true, "clear": true,
+		"compact": true, "config": true, "context": true, "cost": true, "diff": true,
+		"doctor": true, "exit": true, "export": true, "help": true, "hooks": true,
+		"ide": true, "init": true, "login": true, "logout": true, "mcp": true,
+		"memory": true, "mention": true, "model": true, "new": true, "permissions": true,
+		"pr-comments": true, "quit": true, "release-notes": true, "resume": true,
+		"review": true, "rewind": true, "security-review": true, "status": true,
+		"statusline": true, "terminal-setup": true, "todos": true, "undo": true,
+		"upgrade": true, "usage": true, "vim": true,
+	}
+
+	systemPrefixes = []string{
+		"<system-reminder>",
+		"Caveat: The messages below were generated by the user while running local commands",
+		"[Request interrupted by user",
+		"This session is being continued from a previous conversation",
+		"<environment_context>",
+		"<user_instructions>",
+	}
+)
+
+// Classify sets Kind, and Command for slash commands and hooks, on every
+// user entry. A user entry that directly follows a Claude Code slash command
+// invocation holds the expanded command prompt and counts as system text.
+func Classify(entries []*ParsedEntry) {
+	invocations := make(map[string]bool)
+
+	invoked := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.Type == "user" {
+			if m := commandNameTag.FindStringSubmatch(entry.UserContent); m != nil {
+				invoked[strings.TrimPrefix(m[1], "/")] = true
+			}
+		}
+	}
+
+	for _, entry := range entries {
+		if entry.Type != "user" {
+			continue
+		}
+
+		entry.Kind, entry.Command = classifyUserContent(entry.UserContent, invoked)
+		if entry.Kind == KindPrompt && invocations[entry.ParentUUID] {
+			entry.Kind = KindSystem
+		}
+		if entry.Kind == KindSlashCommand && commandNameTag.MatchString(entry.UserContent) {
+			invocations[entry.UUID] = true
+		}
+	}
+}
+
+func classifyUserContent(content string, invoked map[string]bool) (EntryKind, string) {
+	text := strings.TrimSpace(content)
+
+	if m := commandNameTag.FindStringSubmatch(text); m != nil {
+		name := m[1]
+		if !strings.HasPrefix(name, "/") {
+			name = "/" + name
+		}
+		return KindSlashCommand, name
+	}
+	if m := typedCommand.FindStringSubmatch(text); m != nil {
+		if name := m[1][1:]; builtinCommands[name] || invoked[name] || strings.Contains(name, ":") {
+			return KindSlashCommand, m[1]
+		}
+	}
+
+	if commandOutputTag.MatchString(text) {
+		return KindCommandOutput, ""
+	}
+
+	if m := hookMessage.FindStringSubmatch(text); m != nil {
+		if m[2] != "" {
+			return KindHook, m[1] + ":" + m[2]
+		}
+		return KindHook, m[1]
+	}
+	if m := hookTag.FindStringSubmatch(text); m != nil {
+		return KindHook, m[1]
+	}
+
+	for _, prefix := range systemPrefixes {
+		if strings.HasPrefix(text, prefix) {
+			return KindSystem, ""
+		}
+	}
+
+	return KindPrompt, ""
+}
+
+// CommandArgs returns the arguments a slash command was invoked with.
+func (p *ParsedEntry) CommandArgs() string {
+	if m := commandArgsTag.FindStringSubmatch(p.UserContent); m != nil {
+		return strings.TrimSpace(m[1])
+	}
+	if p.Command != "" {
+		return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(p.UserContent), p.Command))
+	}
+	return ""
+}
@@ -6,14 +6,18 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/strrl/auto-flavor/internal/db"
 )
 
 type Parser struct {
-	db       *sql.DB
+	db        *sql.DB
 	claudeDir string
+	columns   map[string]bool
+
+	projectPaths map[string][]string
 }
 
 func NewParser() (*Parser, error) {
@@ -28,61 +32,126 @@ func NewParser() (*Parser, error) {
 	}
 
 	return &Parser{
-		db:       database,
+		db:        database,
 		claudeDir: filepath.Join(homeDir, ".claude", "projects"),
 	}, nil
 }
 
 func (p *Parser) FetchEntriesForProject(projectPath string, since time.Time) ([]*ParsedEntry, error) {
-	var query string
-	var rows *sql.Rows
-	var err error
-
-	if since.IsZero() {
-		query = fmt.Sprintf(`
-			SELECT
-				type,
-				CAST(to_json(message) AS VARCHAR) as message_json,
-				CAST(timestamp AS VARCHAR) as ts,
-				CAST(sessionId AS VARCHAR) as session_id,
-				CAST(uuid AS VARCHAR) as uuid,
-				COALESCE(CAST(parentUuid AS VARCHAR), '') as parent_uuid,
-				COALESCE(cwd, '') as cwd
-			FROM read_json('%s/**/*.jsonl',
-				format = 'newline_delimited',
-				union_by_name = true,
-				ignore_errors = true
-			)
-			WHERE cwd = $1
-			  AND type IN ('user', 'assistant')
-			  AND message IS NOT NULL
-			ORDER BY timestamp ASC
-		`, p.claudeDir)
-		rows, err = p.db.Query(query, projectPath)
-	} else {
-		sinceStr := since.Format("2006-01-02T15:04:05")
-		query = fmt.Sprintf(`
-			SELECT
-				type,
-				CAST(to_json(message) AS VARCHAR) as message_json,
-				CAST(timestamp AS VARCHAR) as ts,
-				CAST(sessionId AS VARCHAR) as session_id,
-				CAST(uuid AS VARCHAR) as uuid,
-				COALESCE(CAST(parentUuid AS VARCHAR), '') as parent_uuid,
-				COALESCE(cwd, '') as cwd
-			FROM read_json('%s/**/*.jsonl',
-				format = 'newline_delimited',
-				union_by_name = true,
-				ignore_errors = true
-			)
-			WHERE cwd = $1
-			  AND timestamp >= $2
-			  AND type IN ('user', 'assistant')
-			  AND message IS NOT NULL
-			ORDER BY timestamp ASC
-		`, p.claudeDir)
-		rows, err = p.db.Query(query, projectPath, sinceStr)
+	return p.fetchEntries("cwd = $1", since, projectPath)
+}
+
+func (p *Parser) FetchEntriesForPaths(paths []string, since time.Time) ([]*ParsedEntry, error) {
+	filter, args := cwdInFilter(paths)
+	return p.fetchEntries(filter, since, args...)
+}
+
+// FetchSession returns the entries of every session whose ID starts with
+// sessionID, so that abbreviated IDs can be used.
+func (p *Parser) FetchSession(sessionID string) ([]*ParsedEntry, error) {
+	return p.fetchEntries("starts_with(CAST(sessionId AS VARCHAR), $1)", time.Time{}, sessionID)
+}
+
+func (p *Parser) FetchAllEntries(since time.Time) ([]*ParsedEntry, error) {
+	return p.fetchEntries("cwd IS NOT NULL AND cwd != ''", since)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+type SearchFilter struct {
+	Terms []string
+	Paths []string
+	Since time.Time
+	Until time.Time
+	Role  string
+}
+
+// SearchEntries returns the entries whose raw message contains every term,
+// case-insensitively, narrowed by the filter. Matches may include JSON keys
+// and tool results, so callers are expected to rank and re-check the text.
+// Only the history files the search index lists for all terms are read.
+func (p *Parser) SearchEntries(f SearchFilter) ([]*ParsedEntry, error) {
+	index, err := p.loadIndex()
+	if err != nil {
+		return nil, err
 	}
+	files := index.candidates(f.Terms)
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	filter, args := "cwd IS NOT NULL AND cwd != ''", []any(nil)
+	if len(f.Paths) > 0 {
+		filter, args = cwdInFilter(f.Paths)
+	}
+
+	for _, term := range f.Terms {
+		args = append(args, "%"+likeEscaper.Replace(term)+"%")
+		filter += fmt.Sprintf(" AND CAST(to_json(message) AS VARCHAR) ILIKE $%d ESCAPE '\\'", len(args))
+	}
+
+	if f.Role != "" {
+		args = append(args, f.Role)
+		filter += fmt.Sprintf(" AND type = $%d", len(args))
+	}
+
+	if !f.Until.IsZero() {
+		args = append(args, f.Until.Format("2006-01-02T15:04:05"))
+		filter += fmt.Sprintf(" AND timestamp < $%d", len(args))
+	}
+
+	return p.fetchEntriesFrom(fileList(files), filter, f.Since, args...)
+}
+
+func (p *Parser) fetchEntries(filter string, since time.Time, args ...any) ([]*ParsedEntry, error) {
+	return p.fetchEntriesFrom(p.historyGlob(), filter, since, args...)
+}
+
+// fetchEntriesFrom reads the history files that source, a read_json file
+// argument, names.
+func (p *Parser) fetchEntriesFrom(source, filter string, since time.Time, args ...any) ([]*ParsedEntry, error) {
+	if !since.IsZero() {
+		args = append(args, since.Format("2006-01-02T15:04:05"))
+		filter += fmt.Sprintf(" AND timestamp >= $%d", len(args))
+	}
+
+	sidechain, agentID := "false", "''"
+	columns, err := p.historyColumns(source)
+	if err != nil {
+		return nil, err
+	}
+	if columns["isSidechain"] {
+		sidechain = "COALESCE(CAST(isSidechain AS BOOLEAN), false)"
+	}
+	if columns["agentId"] {
+		agentID = "COALESCE(CAST(agentId AS VARCHAR), '')"
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			type,
+			CAST(to_json(message) AS VARCHAR) as message_json,
+			CAST(timestamp AS VARCHAR) as ts,
+			CAST(sessionId AS VARCHAR) as session_id,
+			CAST(uuid AS VARCHAR) as uuid,
+			COALESCE(CAST(parentUuid AS VARCHAR), '') as parent_uuid,
+			COALESCE(cwd, '') as cwd,
+			filename as source_file,
+			%s as is_sidechain,
+			%s as agent_id
+		FROM read_json(%s,
+			format = 'newline_delimited',
+			union_by_name = true,
+			ignore_errors = true,
+			filename = true
+		)
+		WHERE %s
+		  AND type IN ('user', 'assistant')
+		  AND message IS NOT NULL
+		ORDER BY timestamp ASC
+	`, sidechain, agentID, source, filter)
+
+	rows, err := p.db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query entries: %w", err)
 	}
@@ -98,22 +167,28 @@ func (p *Parser) FetchEntriesForProject(projectPath string, since time.Time) ([]
 			uuid        string
 			parentUUID  string
 			cwd         string
+			sourceFile  string
+			isSidechain bool
+			agentID     string
 		)
 
-		if err := rows.Scan(&entryType, &messageJSON, &timestamp, &sessionID, &uuid, &parentUUID, &cwd); err != nil {
+		if err := rows.Scan(&entryType, &messageJSON, &timestamp, &sessionID, &uuid, &parentUUID, &cwd, &sourceFile, &isSidechain, &agentID); err != nil {
 			continue
 		}
 
 		ts, _ := time.Parse(time.RFC3339, timestamp)
 
 		entry := &ChatEntry{
-			Type:       entryType,
-			Message:    json.RawMessage(messageJSON),
-			Timestamp:  ts,
-			SessionID:  sessionID,
-			UUID:       uuid,
-			ParentUUID: parentUUID,
-			CWD:        cwd,
+			Type:        entryType,
+			Message:     json.RawMessage(messageJSON),
+			Timestamp:   ts,
+			SessionID:   sessionID,
+			UUID:        uuid,
+			ParentUUID:  parentUUID,
+			CWD:         cwd,
+			SourceFile:  sourceFile,
+			IsSidechain: isSidechain,
+			AgentID:     agentID,
 		}
 
 		parsed, err := entry.Parse()
@@ -128,9 +203,142 @@ func (p *Parser) FetchEntriesForProject(projectPath string, since time.Time) ([]
 		return nil, fmt.Errorf("rows iteration error: %w", err)
 	}
 
+	annotateLines(entries)
+	Classify(entries)
+
 	return entries, nil
 }
 
+// historyGlob is the read_json file argument naming every history file.
+func (p *Parser) historyGlob() string {
+	return quoteLiteral(p.claudeDir + "/**/*.jsonl")
+}
+
+func fileList(files []string) string {
+	quoted := make([]string, len(files))
+	for i, file := range files {
+		quoted[i] = quoteLiteral(file)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// historyColumns lists the columns found across the history files source
+// names. Fields that newer Claude Code versions added, like isSidechain, are
+// missing from older histories, and read_json rejects queries naming a column
+// no file has. The columns of all history files are cached.
+func (p *Parser) historyColumns(source string) (map[string]bool, error) {
+	all := source == p.historyGlob()
+	if all && p.columns != nil {
+		return p.columns, nil
+	}
+
+	query := fmt.Sprintf(`
+		SELECT column_name
+		FROM (DESCRIBE SELECT * FROM read_json(%s,
+			format = 'newline_delimited',
+			union_by_name = true,
+			ignore_errors = true
+		))
+	`, source)
+
+	rows, err := p.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe history files: %w", err)
+	}
+	defer rows.Close()
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			continue
+		}
+		columns[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	if all {
+		p.columns = columns
+	}
+	return columns, nil
+}
+
+type SessionSummary struct {
+	SessionID      string
+	Project        string
+	Start          time.Time
+	End            time.Time
+	UserCount      int
+	AssistantCount int
+}
+
+func (s SessionSummary) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// ListSessions summarizes the sessions recorded in the given directories, or in
+// all directories when paths is empty, most recent first.
+func (p *Parser) ListSessions(paths []string) ([]SessionSummary, error) {
+	filter, args := "cwd IS NOT NULL AND cwd != ''", []any(nil)
+	if len(paths) > 0 {
+		filter, args = cwdInFilter(paths)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			CAST(sessionId AS VARCHAR) as session_id,
+			MIN(cwd) as cwd,
+			MIN(timestamp) as first,
+			MAX(timestamp) as last,
+			COUNT(*) FILTER (WHERE type = 'user') as user_count,
+			COUNT(*) FILTER (WHERE type = 'assistant') as assistant_count
+		FROM read_json('%s/**/*.jsonl',
+			format = 'newline_delimited',
+			union_by_name = true,
+			ignore_errors = true
+		)
+		WHERE %s
+		  AND sessionId IS NOT NULL
+		  AND type IN ('user', 'assistant')
+		GROUP BY sessionId
+		ORDER BY MAX(timestamp) DESC
+	`, p.claudeDir, filter)
+
+	rows, err := p.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []SessionSummary
+	for rows.Next() {
+		var (
+			summary     SessionSummary
+			first, last sql.NullString
+		)
+
+		if err := rows.Scan(&summary.SessionID, &summary.Project, &first, &last, &summary.UserCount, &summary.AssistantCount); err != nil {
+			continue
+		}
+
+		summary.Start, _ = time.Parse(time.RFC3339, first.String)
+		summary.End, _ = time.Parse(time.RFC3339, last.String)
+		sessions = append(sessions, summary)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return sessions, nil
+}
+
 func (p *Parser) ListProjects() ([]string, error) {
 	query := fmt.Sprintf(`
 		SELECT DISTINCT cwd
@@ -161,7 +369,62 @@ func (p *Parser) ListProjects() ([]string, error) {
 	return projects, nil
 }
 
+// recordedBranches lists, per working directory, the git branches the history
+// recorded sessions on. Histories older than the gitBranch field yield none.
+func (p *Parser) recordedBranches() (map[string][]string, error) {
+	columns, err := p.historyColumns(p.historyGlob())
+	if err != nil {
+		return nil, err
+	}
+	if !columns["gitBranch"] {
+		return nil, nil
+	}
+
+	query := fmt.Sprintf(`
+		SELECT DISTINCT cwd, CAST(gitBranch AS VARCHAR)
+		FROM read_json('%s/**/*.jsonl',
+			format = 'newline_delimited',
+			union_by_name = true,
+			ignore_errors = true
+		)
+		WHERE cwd IS NOT NULL AND cwd != '' AND gitBranch IS NOT NULL
+	`, p.claudeDir)
+
+	rows, err := p.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches: %w", err)
+	}
+	defer rows.Close()
+
+	branches := make(map[string][]string)
+	for rows.Next() {
+		var cwd, branch string
+		if err := rows.Scan(&cwd, &branch); err != nil {
+			continue
+		}
+		branches[cwd] = append(branches[cwd], branch)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return branches, nil
+}
+
 func (p *Parser) GetProjectStats(projectPath string) (int, time.Time, time.Time, error) {
+	return p.getStats("cwd = $1", projectPath)
+}
+
+func (p *Parser) GetPathsStats(paths []string) (int, time.Time, time.Time, error) {
+	filter, args := cwdInFilter(paths)
+	return p.getStats(filter, args...)
+}
+
+func (p *Parser) GetGlobalStats() (int, time.Time, time.Time, error) {
+	return p.getStats("cwd IS NOT NULL AND cwd != ''")
+}
+
+func (p *Parser) getStats(filter string, args ...any) (int, time.Time, time.Time, error) {
 	query := fmt.Sprintf(`
 		SELECT
 			COUNT(*) as count,
@@ -172,14 +435,14 @@ func (p *Parser) GetProjectStats(projectPath string) (int, time.Time, time.Time,
 			union_by_name = true,
 			ignore_errors = true
 		)
-		WHERE cwd = $1
+		WHERE %s
 		  AND type IN ('user', 'assistant')
-	`, p.claudeDir)
+	`, p.claudeDir, filter)
 
 	var count int
 	var firstStr, lastStr sql.NullString
 
-	err := p.db.QueryRow(query, projectPath).Scan(&count, &firstStr, &lastStr)
+	err := p.db.QueryRow(query, args...).Scan(&count, &firstStr, &lastStr)
 	if err != nil {
 		return 0, time.Time{}, time.Time{}, fmt.Errorf("failed to get stats: %w", err)
 	}
@@ -194,3 +457,18 @@ func (p *Parser) GetProjectStats(projectPath string) (int, time.Time, time.Time,
 
 	return count, first, last, nil
 }
+
+func cwdInFilter(paths []string) (string, []any) {
+	if len(paths) == 0 {
+		return "FALSE", nil
+	}
+
+	placeholders := make([]string, len(paths))
+	args := make([]any, len(paths))
+	for i, path := range paths {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = path
+	}
+
+	return "cwd IN (" + strings.Join(placeholders, ", ") + ")", args
+}
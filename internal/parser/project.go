@@ -0,0 +1,226 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+type MatchOptions struct {
+	IncludeSubdirs bool
+	MatchGitRoot   bool
+	MatchRemote    bool
+	// MatchMoved also matches recorded directories that no longer exist when
+	// they look like an earlier location of the repository.
+	MatchMoved bool
+}
+
+// ProjectIdentity describes where a directory lives in terms of git, so that
+// subdirectories, worktrees and clones of one repository can be recognized as
+// the same project.
+type ProjectIdentity struct {
+	Path         string
+	GitRoot      string
+	GitCommonDir string
+	RemoteURL    string
+}
+
+var (
+	resolvedMu sync.Mutex
+	resolved   = make(map[string]ProjectIdentity)
+)
+
+// ResolveProject looks up the git identity of path. Lookups are cached for the
+// life of the process, since each one runs git several times.
+func ResolveProject(path string) ProjectIdentity {
+	path = filepath.Clean(path)
+
+	resolvedMu.Lock()
+	id, ok := resolved[path]
+	resolvedMu.Unlock()
+	if ok {
+		return id
+	}
+
+	id = resolveProject(path)
+
+	resolvedMu.Lock()
+	resolved[path] = id
+	resolvedMu.Unlock()
+	return id
+}
+
+func resolveProject(path string) ProjectIdentity {
+	id := ProjectIdentity{Path: path}
+
+	if root, err := runGit(path, "rev-parse", "--show-toplevel"); err == nil {
+		id.GitRoot = filepath.Clean(root)
+	}
+
+	if commonDir, err := runGit(path, "rev-parse", "--path-format=absolute", "--git-common-dir"); err == nil {
+		id.GitCommonDir = filepath.Clean(commonDir)
+	}
+
+	if remote, err := runGit(path, "config", "--get", "remote.origin.url"); err == nil {
+		id.RemoteURL = NormalizeRemoteURL(remote)
+	}
+
+	return id
+}
+
+// Root is the directory that history is matched against: the git root when
+// the path is inside a repository, otherwise the path itself.
+func (id ProjectIdentity) Root() string {
+	if id.GitRoot != "" {
+		return id.GitRoot
+	}
+	return id.Path
+}
+
+var scpLikeRemote = regexp.MustCompile(`^(?:[^@/]+@)?([^:/]+):(.+)$`)
+
+func NormalizeRemoteURL(remote string) string {
+	remote = strings.TrimSpace(remote)
+	if remote == "" {
+		return ""
+	}
+
+	if i := strings.Index(remote, "://"); i >= 0 {
+		remote = remote[i+3:]
+		if at := strings.Index(remote, "@"); at >= 0 && at < strings.Index(remote+"/", "/") {
+			remote = remote[at+1:]
+		}
+	} else if m := scpLikeRemote.FindStringSubmatch(remote); m != nil {
+		remote = m[1] + "/" + m[2]
+	}
+
+	remote = strings.TrimSuffix(remote, "/")
+	remote = strings.TrimSuffix(remote, ".git")
+	return strings.ToLower(remote)
+}
+
+// ResolveProjectPaths returns every recorded working directory that belongs to
+// the project at projectPath according to opts. Results are cached on the
+// parser.
+func (p *Parser) ResolveProjectPaths(projectPath string, opts MatchOptions) ([]string, error) {
+	cacheKey := fmt.Sprintf("%s|%+v", filepath.Clean(projectPath), opts)
+	if paths, ok := p.projectPaths[cacheKey]; ok {
+		return paths, nil
+	}
+
+	target := ResolveProject(projectPath)
+
+	root := target.Path
+	if opts.MatchGitRoot {
+		root = target.Root()
+	}
+
+	candidates, err := p.ListProjects()
+	if err != nil {
+		return nil, err
+	}
+
+	var branches map[string][]string
+	if opts.MatchMoved && target.RemoteURL != "" {
+		if branches, err = p.recordedBranches(); err != nil {
+			return nil, err
+		}
+	}
+
+	var paths []string
+	for _, cwd := range candidates {
+		if matchesProject(cwd, target, root, opts) || matchesMovedRepo(cwd, target, branches[cwd]) {
+			paths = append(paths, cwd)
+		}
+	}
+
+	if p.projectPaths == nil {
+		p.projectPaths = make(map[string][]string)
+	}
+	p.projectPaths[cacheKey] = paths
+	return paths, nil
+}
+
+func matchesProject(cwd string, target ProjectIdentity, root string, opts MatchOptions) bool {
+	cwd = filepath.Clean(cwd)
+
+	if cwd == target.Path || cwd == root {
+		return true
+	}
+
+	if opts.IncludeSubdirs && isWithin(cwd, root) {
+		return true
+	}
+
+	if !opts.MatchGitRoot && !opts.MatchRemote {
+		return false
+	}
+
+	if _, err := os.Stat(cwd); err != nil {
+		return false
+	}
+
+	candidate := ResolveProject(cwd)
+
+	if opts.MatchGitRoot && target.GitCommonDir != "" && candidate.GitCommonDir == target.GitCommonDir {
+		return opts.IncludeSubdirs || candidate.GitRoot == candidate.Path
+	}
+
+	if opts.MatchRemote && target.RemoteURL != "" && candidate.RemoteURL == target.RemoteURL {
+		return opts.IncludeSubdirs || candidate.GitRoot == candidate.Path
+	}
+
+	return false
+}
+
+// defaultBranches exist in nearly every repository and say nothing about
+// whether two directories held the same one.
+var defaultBranches = []string{"", "HEAD", "main", "master", "develop", "trunk"}
+
+// matchesMovedRepo treats a directory that no longer exists as an earlier
+// location of the target repository when its name matches the repository
+// name in the remote URL and a branch the history recorded there, other than
+// a default branch, exists in the target too.
+func matchesMovedRepo(cwd string, target ProjectIdentity, branches []string) bool {
+	if target.RemoteURL == "" || len(branches) == 0 {
+		return false
+	}
+	if !strings.EqualFold(filepath.Base(cwd), filepath.Base(target.RemoteURL)) {
+		return false
+	}
+	if _, err := os.Stat(cwd); err == nil {
+		return false
+	}
+
+	for _, branch := range branches {
+		if slices.Contains(defaultBranches, branch) {
+			continue
+		}
+		if _, err := runGit(target.Root(), "rev-parse", "--verify", "--quiet", "refs/heads/"+branch); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func isWithin(path, root string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
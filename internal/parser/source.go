@@ -0,0 +1,107 @@
+package parser
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// annotateLines fills in SourceLine for entries by scanning each referenced
+// JSONL file once and locating the line that holds the entry's UUID. A file
+// that cannot be read is skipped with a warning and its entries keep no line.
+func annotateLines(entries []*ParsedEntry) {
+	byFile := make(map[string][]*ParsedEntry)
+	for _, entry := range entries {
+		if entry.SourceFile != "" && entry.UUID != "" {
+			byFile[entry.SourceFile] = append(byFile[entry.SourceFile], entry)
+		}
+	}
+
+	for file, fileEntries := range byFile {
+		lines, err := indexUUIDLines(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+			continue
+		}
+		for _, entry := range fileEntries {
+			entry.SourceLine = lines[entry.UUID]
+		}
+	}
+}
+
+func indexUUIDLines(path string) (map[string]int, error) {
+	lines := make(map[string]int)
+
+	err := scanJSONL(path, func(lineNo int, line []byte) bool {
+		var probe struct {
+			UUID string `json:"uuid"`
+		}
+		if json.Unmarshal(line, &probe) == nil && probe.UUID != "" {
+			lines[probe.UUID] = lineNo
+		}
+		return true
+	})
+
+	return lines, err
+}
+
+// ReadEntriesAround parses the entries of one JSONL file whose line numbers
+// fall within [line-before, line+after], keeping only the given session.
+func ReadEntriesAround(path string, line, before, after int, sessionID string) ([]*ParsedEntry, error) {
+	var entries []*ParsedEntry
+
+	err := scanJSONL(path, func(lineNo int, raw []byte) bool {
+		if lineNo < line-before {
+			return true
+		}
+		if lineNo > line+after {
+			return false
+		}
+
+		var entry ChatEntry
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			return true
+		}
+		if entry.Type != "user" && entry.Type != "assistant" {
+			return true
+		}
+		if sessionID != "" && entry.SessionID != sessionID {
+			return true
+		}
+
+		entry.SourceFile = path
+		entry.SourceLine = lineNo
+		if parsed, err := entry.Parse(); err == nil {
+			entries = append(entries, parsed)
+		}
+		return true
+	})
+	Classify(entries)
+
+	return entries, err
+}
+
+func scanJSONL(path string, fn func(lineNo int, line []byte) bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	for lineNo := 1; ; lineNo++ {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 && !fn(lineNo, line) {
+			return nil
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	}
+}
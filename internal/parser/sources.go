@@ -0,0 +1,169 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Source is a history of conversations with one coding agent, normalized
+// into ParsedEntry values so that detection works the same for every agent.
+type Source interface {
+	Name() string
+	Fetch(filter SourceFilter) ([]*ParsedEntry, error)
+}
+
+// SourceFilter selects entries of one project, matched like
+// ResolveProjectPaths does, or of every project when Project is empty.
+type SourceFilter struct {
+	Project string
+	Match   MatchOptions
+	Since   time.Time
+}
+
+type SourcesConfig struct {
+	Enabled  []string `yaml:"enabled"`
+	CodexDir string   `yaml:"codex_dir"`
+	Exports  []string `yaml:"exports"`
+}
+
+func DefaultSourcesConfig() SourcesConfig {
+	return SourcesConfig{
+		Enabled: SourceNames(),
+	}
+}
+
+func SourceNames() []string {
+	return []string{"claude", "codex", "aider", "export"}
+}
+
+// Sources returns the enabled sources. Claude Code history is read through
+// the parser's database; the other agents' histories are read from disk.
+func (p *Parser) Sources(cfg SourcesConfig) ([]Source, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	var sources []Source
+	for _, name := range cfg.Enabled {
+		switch name {
+		case "claude":
+			sources = append(sources, claudeSource{parser: p})
+		case "codex":
+			dir := cfg.CodexDir
+			if dir == "" {
+				dir = filepath.Join(homeDir, ".codex", "sessions")
+			}
+			sources = append(sources, codexSource{dir: expandHome(dir, homeDir)})
+		case "aider":
+			sources = append(sources, aiderSource{parser: p})
+		case "export":
+			var dirs []string
+			for _, dir := range cfg.Exports {
+				dirs = append(dirs, expandHome(dir, homeDir))
+			}
+			sources = append(sources, exportSource{dirs: dirs})
+		default:
+			return nil, fmt.Errorf("unknown history source %q (want one of %s)", name, strings.Join(SourceNames(), ", "))
+		}
+	}
+
+	return sources, nil
+}
+
+// FetchFromSources merges the entries of all sources in time order and
+// reports how many entries each source contributed.
+func FetchFromSources(sources []Source, filter SourceFilter) ([]*ParsedEntry, map[string]int, error) {
+	var entries []*ParsedEntry
+	counts := make(map[string]int)
+
+	for _, source := range sources {
+		fetched, err := source.Fetch(filter)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s history: %w", source.Name(), err)
+		}
+
+		for _, entry := range fetched {
+			entry.Source = source.Name()
+		}
+		counts[source.Name()] = len(fetched)
+		entries = append(entries, fetched...)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.Before(entries[j].Timestamp)
+	})
+	Classify(entries)
+
+	return entries, counts, nil
+}
+
+type claudeSource struct {
+	parser *Parser
+}
+
+func (s claudeSource) Name() string { return "claude" }
+
+func (s claudeSource) Fetch(filter SourceFilter) ([]*ParsedEntry, error) {
+	if filter.Project == "" {
+		return s.parser.FetchAllEntries(filter.Since)
+	}
+
+	paths, err := s.parser.ResolveProjectPaths(filter.Project, filter.Match)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	return s.parser.FetchEntriesForPaths(paths, filter.Since)
+}
+
+// projectMatcher reports whether a recorded working directory passes the
+// filter, caching the git lookups that matching may need.
+func (f SourceFilter) projectMatcher() func(cwd string) bool {
+	if f.Project == "" {
+		return func(cwd string) bool { return cwd != "" }
+	}
+
+	target := ResolveProject(f.Project)
+	root := target.Path
+	if f.Match.MatchGitRoot {
+		root = target.Root()
+	}
+
+	cache := make(map[string]bool)
+	return func(cwd string) bool {
+		if cwd == "" {
+			return false
+		}
+		matched, ok := cache[cwd]
+		if !ok {
+			matched = matchesProject(cwd, target, root, f.Match)
+			cache[cwd] = matched
+		}
+		return matched
+	}
+}
+
+func (f SourceFilter) keep(entry *ParsedEntry, matches func(string) bool) bool {
+	if !f.Since.IsZero() && entry.Timestamp.Before(f.Since) {
+		return false
+	}
+	return matches(entry.CWD)
+}
+
+func expandHome(path, homeDir string) string {
+	if path == "~" {
+		return homeDir
+	}
+	if strings.HasPrefix(path, "~/") {
+		return filepath.Join(homeDir, path[2:])
+	}
+	return path
+}
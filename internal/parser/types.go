@@ -2,6 +2,8 @@ package parser
 
 import (
 	"encoding/json"
+	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -13,11 +15,17 @@ type ChatEntry struct {
 	UUID       string          `json:"uuid"`
 	ParentUUID string          `json:"parentUuid"`
 	CWD        string          `json:"cwd"`
+
+	IsSidechain bool   `json:"isSidechain"`
+	AgentID     string `json:"agentId"`
+
+	SourceFile string `json:"-"`
+	SourceLine int    `json:"-"`
 }
 
 type UserMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
 }
 
 type AssistantMessage struct {
@@ -31,6 +39,36 @@ type ContentBlock struct {
 	ID    string          `json:"id,omitempty"`
 	Name  string          `json:"name,omitempty"`
 	Input json.RawMessage `json:"input,omitempty"`
+
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   json.RawMessage `json:"content,omitempty"`
+	IsError   bool            `json:"is_error,omitempty"`
+}
+
+// ResultText returns the textual content of a tool_result block, whose
+// content may be either a plain string or a list of text blocks.
+func (b ContentBlock) ResultText() string {
+	if len(b.Content) == 0 {
+		return ""
+	}
+
+	var text string
+	if err := json.Unmarshal(b.Content, &text); err == nil {
+		return text
+	}
+
+	var blocks []ContentBlock
+	if err := json.Unmarshal(b.Content, &blocks); err == nil {
+		var parts []string
+		for _, block := range blocks {
+			if block.Type == "text" {
+				parts = append(parts, block.Text)
+			}
+		}
+		return strings.Join(parts, "\n")
+	}
+
+	return string(b.Content)
 }
 
 type EditToolInput struct {
@@ -63,9 +101,24 @@ type ParsedEntry struct {
 	UUID       string
 	ParentUUID string
 	CWD        string
+	SourceFile string
+	SourceLine int
+	Source     string
+
+	// Sidechain is set on the entries of a subagent's conversation, whose
+	// user turns are prompts written by the parent agent. AgentID names the
+	// subagent when the history records it.
+	Sidechain bool
+	AgentID   string
 
 	UserContent      string
+	UserBlocks       []ContentBlock
 	AssistantContent []ContentBlock
+
+	// Kind is set on user entries by Classify. Command names the slash
+	// command or hook of such entries.
+	Kind    EntryKind
+	Command string
 }
 
 func (e *ChatEntry) Parse() (*ParsedEntry, error) {
@@ -76,6 +129,10 @@ func (e *ChatEntry) Parse() (*ParsedEntry, error) {
 		UUID:       e.UUID,
 		ParentUUID: e.ParentUUID,
 		CWD:        e.CWD,
+		SourceFile: e.SourceFile,
+		SourceLine: e.SourceLine,
+		Sidechain:  e.IsSidechain || isAgentFile(e.SourceFile),
+		AgentID:    e.AgentID,
 	}
 
 	if e.Type == "user" {
@@ -83,7 +140,7 @@ func (e *ChatEntry) Parse() (*ParsedEntry, error) {
 		if err := json.Unmarshal(e.Message, &userMsg); err != nil {
 			parsed.UserContent = string(e.Message)
 		} else {
-			parsed.UserContent = userMsg.Content
+			parsed.UserContent, parsed.UserBlocks = parseUserContent(userMsg.Content)
 		}
 	} else if e.Type == "assistant" {
 		var assistantMsg AssistantMessage
@@ -95,6 +152,43 @@ func (e *ChatEntry) Parse() (*ParsedEntry, error) {
 	return parsed, nil
 }
 
+// isAgentFile reports whether path holds a subagent transcript, which
+// Claude Code writes to agent-<id>.jsonl files next to the session's.
+func isAgentFile(path string) bool {
+	return strings.HasPrefix(filepath.Base(path), "agent-") && strings.HasSuffix(path, ".jsonl")
+}
+
+func parseUserContent(raw json.RawMessage) (string, []ContentBlock) {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text, nil
+	}
+
+	var blocks []ContentBlock
+	if err := json.Unmarshal(raw, &blocks); err != nil {
+		return string(raw), nil
+	}
+
+	var parts []string
+	for _, block := range blocks {
+		if block.Type == "text" {
+			parts = append(parts, block.Text)
+		}
+	}
+
+	return strings.Join(parts, "\n"), blocks
+}
+
+func (p *ParsedEntry) GetToolResults() []ContentBlock {
+	var results []ContentBlock
+	for _, block := range p.UserBlocks {
+		if block.Type == "tool_result" {
+			results = append(results, block)
+		}
+	}
+	return results
+}
+
 func (p *ParsedEntry) GetToolUses() []ContentBlock {
 	var tools []ContentBlock
 	for _, block := range p.AssistantContent {
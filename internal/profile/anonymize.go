@@ -0,0 +1,264 @@
+package profile
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/strrl/auto-flavor/internal/signals"
+)
+
+const (
+	EvidenceHash   = "hash"
+	EvidenceRedact = "redact"
+
+	CoarsenDay   = "day"
+	CoarsenWeek  = "week"
+	CoarsenMonth = "month"
+)
+
+type AnonymizeOptions struct {
+	Evidence    string
+	Coarsen     string
+	ProjectRoot string
+}
+
+var homePath = regexp.MustCompile(`(/Users|/home|[A-Za-z]:\\Users)[/\\][^/\\\s]+`)
+
+// messageCategories hold the user's whole message as their value, and its
+// first clause as their key: approvals and corrections that reject or redo
+// something without stating a rule.
+// Prohibitions, requirements and preferences also keep the message, but it
+// is the rule handed to assistants, so it stays readable.
+var messageCategories = map[string]bool{
+	"approval":   true,
+	"rejection":  true,
+	"correction": true,
+}
+
+// Anonymize returns a copy of exported that is safe to share: evidence text
+// and values that are only a message are hashed or redacted, absolute paths
+// are made relative to the project root, usernames are removed and
+// timestamps are coarsened. Rule text, including the message a prohibition,
+// requirement or preference was stated in, and scores are kept so that the
+// profile can still be merged.
+func Anonymize(exported *Exported, opts AnonymizeOptions) (*Exported, error) {
+	a, err := newAnonymizer(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	src := exported.Profile
+	dst := &signals.FlavorProfile{
+		CreatedAt:        a.time(src.CreatedAt),
+		AnalyzedMessages: src.AnalyzedMessages,
+		TimeRange: signals.TimeRange{
+			Start: a.time(src.TimeRange.Start),
+			End:   a.time(src.TimeRange.End),
+		},
+		StackPreferences: a.preferences(src.StackPreferences),
+		StylePreferences: a.preferences(src.StylePreferences),
+		Approvals:        a.preferences(src.Approvals),
+		Corrections:      a.preferences(src.Corrections),
+	}
+
+	for _, c := range src.Conflicts {
+		conflict := signals.ConflictingPreference{
+			Category: c.Category,
+			Key:      a.value(c.Category, c.Key),
+			Projects: a.paths(c.Projects),
+		}
+		for _, v := range c.Values {
+			conflict.Values = append(conflict.Values, signals.ConflictValue{
+				Value:       a.value(c.Category, v.Value),
+				Timestamp:   a.time(v.Timestamp),
+				SignalCount: v.SignalCount,
+				Strength:    v.Strength,
+			})
+		}
+		dst.Conflicts = append(dst.Conflicts, conflict)
+	}
+
+	for _, s := range src.Superseded {
+		dst.Superseded = append(dst.Superseded, signals.SupersededPreference{
+			Category:    s.Category,
+			Key:         a.value(s.Category, s.Key),
+			OldValue:    a.value(s.Category, s.OldValue),
+			NewValue:    a.value(s.Category, s.NewValue),
+			SwitchedAt:  a.time(s.SwitchedAt),
+			OldCount:    s.OldCount,
+			NewCount:    s.NewCount,
+			OldLastSeen: a.time(s.OldLastSeen),
+			NewLastSeen: a.time(s.NewLastSeen),
+			Projects:    a.paths(s.Projects),
+		})
+	}
+
+	return &Exported{
+		Version: exported.Version,
+		Project: filepath.Base(exported.Project),
+		Profile: dst,
+	}, nil
+}
+
+type anonymizer struct {
+	opts     AnonymizeOptions
+	inRoot   *regexp.Regexp
+	root     *regexp.Regexp
+	home     *regexp.Regexp
+	username *regexp.Regexp
+}
+
+// pathEnd follows a directory where it ends a path rather than being the
+// start of a sibling's name, like /app in /app-old.
+const pathEnd = `([^\w.-]|$)`
+
+func newAnonymizer(opts AnonymizeOptions) (*anonymizer, error) {
+	switch opts.Evidence {
+	case "":
+		opts.Evidence = EvidenceHash
+	case EvidenceHash, EvidenceRedact:
+	default:
+		return nil, fmt.Errorf("unknown evidence mode %q (want %s or %s)", opts.Evidence, EvidenceHash, EvidenceRedact)
+	}
+
+	switch opts.Coarsen {
+	case "":
+		opts.Coarsen = CoarsenDay
+	case CoarsenDay, CoarsenWeek, CoarsenMonth:
+	default:
+		return nil, fmt.Errorf("unknown time granularity %q (want %s, %s or %s)", opts.Coarsen, CoarsenDay, CoarsenWeek, CoarsenMonth)
+	}
+
+	a := &anonymizer{opts: opts}
+	if root := strings.TrimSuffix(opts.ProjectRoot, "/"); root != "" {
+		a.inRoot = regexp.MustCompile(regexp.QuoteMeta(root) + `/`)
+		a.root = regexp.MustCompile(regexp.QuoteMeta(root) + pathEnd)
+	}
+	if homeDir, _ := os.UserHomeDir(); homeDir != "" {
+		a.home = regexp.MustCompile(regexp.QuoteMeta(strings.TrimSuffix(homeDir, "/")) + pathEnd)
+	}
+	if u, err := user.Current(); err == nil && len(u.Username) > 2 {
+		a.username = regexp.MustCompile(`\b` + regexp.QuoteMeta(u.Username) + `\b`)
+	}
+
+	return a, nil
+}
+
+func (a *anonymizer) preferences(prefs []signals.Preference) []signals.Preference {
+	var out []signals.Preference
+
+	for _, p := range prefs {
+		pref := p
+		pref.Key = a.value(p.Category, p.Key)
+		pref.Value = a.value(p.Category, p.Value)
+		pref.FirstSeen = a.time(p.FirstSeen)
+		pref.LastSeen = a.time(p.LastSeen)
+		pref.Projects = a.paths(p.Projects)
+		pref.Members = nil
+		pref.Evidence = nil
+
+		for _, e := range p.Evidence {
+			pref.Evidence = append(pref.Evidence, signals.Evidence{
+				Text:      a.evidence(e.Text),
+				Context:   a.evidence(e.Context),
+				Timestamp: a.time(e.Timestamp),
+				SessionID: a.hash(e.SessionID),
+				Project:   a.path(e.Project),
+
+				MessageUUID: a.hash(e.MessageUUID),
+				SourceFile:  a.hash(e.SourceFile),
+				SourceLine:  e.SourceLine,
+			})
+		}
+
+		out = append(out, pref)
+	}
+
+	return out
+}
+
+func (a *anonymizer) value(category, s string) string {
+	if messageCategories[category] {
+		return a.evidence(s)
+	}
+	return a.text(s)
+}
+
+func (a *anonymizer) evidence(s string) string {
+	if s == "" {
+		return ""
+	}
+	if a.opts.Evidence == EvidenceRedact {
+		return "[redacted]"
+	}
+	return "sha256:" + a.hash(s)
+}
+
+func (a *anonymizer) hash(s string) string {
+	if s == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// text keeps rule text readable while stripping paths and usernames from it.
+func (a *anonymizer) text(s string) string {
+	if a.root != nil {
+		s = a.inRoot.ReplaceAllString(s, "")
+		s = a.root.ReplaceAllString(s, ".${1}")
+	}
+	if a.home != nil {
+		s = a.home.ReplaceAllString(s, "~${1}")
+	}
+	s = homePath.ReplaceAllString(s, "~")
+	if a.username != nil {
+		s = a.username.ReplaceAllString(s, "<user>")
+	}
+	return s
+}
+
+func (a *anonymizer) path(p string) string {
+	if p == "" {
+		return ""
+	}
+	if a.opts.ProjectRoot != "" {
+		if rel, err := filepath.Rel(a.opts.ProjectRoot, p); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+	return a.text(p)
+}
+
+func (a *anonymizer) paths(paths []string) []string {
+	var out []string
+	for _, p := range paths {
+		out = append(out, a.path(p))
+	}
+	return out
+}
+
+func (a *anonymizer) time(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+
+	t = t.UTC()
+	switch a.opts.Coarsen {
+	case CoarsenWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	case CoarsenMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+}
@@ -0,0 +1,62 @@
+package profile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/strrl/auto-flavor/internal/signals"
+)
+
+const FormatVersion = 1
+
+// Exported is the on-disk form of a flavor profile, as shared between
+// developers and consumed by the merge command.
+type Exported struct {
+	Version int                    `json:"version"`
+	Author  string                 `json:"author,omitempty"`
+	Project string                 `json:"project,omitempty"`
+	Profile *signals.FlavorProfile `json:"profile"`
+}
+
+func Save(path string, exported *Exported) error {
+	exported.Version = FormatVersion
+
+	data, err := json.MarshalIndent(exported, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode profile: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create profile directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write profile: %w", err)
+	}
+
+	return nil
+}
+
+func Load(path string) (*Exported, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile: %w", err)
+	}
+
+	var exported Exported
+	if err := json.Unmarshal(data, &exported); err != nil {
+		return nil, fmt.Errorf("failed to parse profile %s: %w", path, err)
+	}
+
+	if exported.Version > FormatVersion {
+		return nil, fmt.Errorf("profile %s has unsupported version %d", path, exported.Version)
+	}
+
+	if exported.Profile == nil {
+		return nil, fmt.Errorf("profile %s is empty", path)
+	}
+
+	return &exported, nil
+}
@@ -0,0 +1,140 @@
+package query
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/marcboeker/go-duckdb"
+)
+
+const (
+	FormatTable = "table"
+	FormatCSV   = "csv"
+	FormatJSON  = "json"
+)
+
+func Write(w io.Writer, result *Result, format string, maxWidth int) error {
+	switch format {
+	case FormatTable, "":
+		return writeTable(w, result, maxWidth)
+	case FormatCSV:
+		return writeCSV(w, result)
+	case FormatJSON:
+		return writeJSON(w, result)
+	default:
+		return fmt.Errorf("unknown output format %q (want %s, %s or %s)", format, FormatTable, FormatCSV, FormatJSON)
+	}
+}
+
+func writeTable(w io.Writer, result *Result, maxWidth int) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(result.Columns, "\t"))
+
+	for _, row := range result.Rows {
+		cells := make([]string, len(row))
+		for i, value := range row {
+			cell := strings.Join(strings.Fields(formatValue(value)), " ")
+			if maxWidth > 0 && len(cell) > maxWidth {
+				cell = cell[:maxWidth] + "..."
+			}
+			cells[i] = cell
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	rows := "rows"
+	if len(result.Rows) == 1 {
+		rows = "row"
+	}
+	_, err := fmt.Fprintf(w, "(%d %s)\n", len(result.Rows), rows)
+	return err
+}
+
+func writeCSV(w io.Writer, result *Result) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(result.Columns); err != nil {
+		return err
+	}
+
+	for _, row := range result.Rows {
+		record := make([]string, len(row))
+		for i, value := range row {
+			record[i] = formatValue(value)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeJSON(w io.Writer, result *Result) error {
+	records := make([]map[string]any, 0, len(result.Rows))
+	for _, row := range result.Rows {
+		record := make(map[string]any, len(row))
+		for i, value := range row {
+			record[result.Columns[i]] = jsonValue(value)
+		}
+		records = append(records, record)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(records)
+}
+
+func formatValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case []byte:
+		return string(v)
+	case duckdb.Interval:
+		return formatInterval(v)
+	case map[string]any, []any:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func jsonValue(value any) any {
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case duckdb.Interval:
+		return formatInterval(v)
+	case fmt.Stringer:
+		return v.String()
+	default:
+		if _, err := json.Marshal(v); err != nil {
+			return fmt.Sprint(v)
+		}
+		return v
+	}
+}
+
+func formatInterval(v duckdb.Interval) string {
+	d := time.Duration(v.Days)*24*time.Hour + time.Duration(v.Micros)*time.Microsecond
+	if v.Months != 0 {
+		return fmt.Sprintf("%d months %s", v.Months, d)
+	}
+	return d.String()
+}
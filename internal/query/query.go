@@ -0,0 +1,312 @@
+package query
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/marcboeker/go-duckdb"
+	"github.com/strrl/auto-flavor/internal/db"
+	"github.com/strrl/auto-flavor/internal/parser"
+	"github.com/strrl/auto-flavor/internal/signals"
+)
+
+// Views lists the normalized tables available to queries, with a short
+// description of each.
+var Views = []struct {
+	Name        string
+	Description string
+}{
+	{"entries", "one row per user or assistant message, with its text"},
+	{"tool_uses", "one row per tool call made by the assistant"},
+	{"tool_results", "one row per tool result returned to the assistant"},
+	{"sessions", "one row per session with message counts and time span"},
+	{"signals", "one row per preference signal found by the detector"},
+}
+
+var schema = []string{
+	`CREATE OR REPLACE TABLE entries (
+		uuid VARCHAR,
+		parent_uuid VARCHAR,
+		session_id VARCHAR,
+		project VARCHAR,
+		type VARCHAR,
+		timestamp TIMESTAMP,
+		text VARCHAR,
+		source_file VARCHAR,
+		source_line INTEGER
+	)`,
+	`CREATE OR REPLACE TABLE tool_uses (
+		id VARCHAR,
+		uuid VARCHAR,
+		session_id VARCHAR,
+		project VARCHAR,
+		timestamp TIMESTAMP,
+		name VARCHAR,
+		input JSON
+	)`,
+	`CREATE OR REPLACE TABLE tool_results (
+		tool_use_id VARCHAR,
+		uuid VARCHAR,
+		session_id VARCHAR,
+		project VARCHAR,
+		timestamp TIMESTAMP,
+		is_error BOOLEAN,
+		content VARCHAR
+	)`,
+	`CREATE OR REPLACE TABLE signals (
+		type VARCHAR,
+		category VARCHAR,
+		key VARCHAR,
+		value VARCHAR,
+		strength INTEGER,
+		timestamp TIMESTAMP,
+		session_id VARCHAR,
+		project VARCHAR,
+		message_uuid VARCHAR,
+		context VARCHAR
+	)`,
+	`CREATE OR REPLACE VIEW sessions AS
+		SELECT
+			session_id,
+			MIN(project) AS project,
+			MIN(timestamp) AS started_at,
+			MAX(timestamp) AS ended_at,
+			MAX(timestamp) - MIN(timestamp) AS duration,
+			COUNT(*) FILTER (WHERE type = 'user') AS user_messages,
+			COUNT(*) FILTER (WHERE type = 'assistant') AS assistant_messages,
+			(SELECT COUNT(*) FROM tool_uses t WHERE t.session_id = e.session_id) AS tool_uses,
+			(SELECT COUNT(*) FROM signals s WHERE s.session_id = e.session_id) AS signals
+		FROM entries e
+		GROUP BY session_id`,
+}
+
+type Engine struct {
+	db *sql.DB
+}
+
+// NewEngine materializes the normalized views from already parsed entries and
+// detected signals, then locks the database down to read-only queries.
+func NewEngine(entries []*parser.ParsedEntry, sigs []signals.Signal) (*Engine, error) {
+	database, err := db.GetDB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database: %w", err)
+	}
+
+	for _, stmt := range schema {
+		if _, err := database.Exec(stmt); err != nil {
+			return nil, fmt.Errorf("failed to create schema: %w", err)
+		}
+	}
+
+	if err := load(database, entries, sigs); err != nil {
+		return nil, err
+	}
+
+	if _, err := database.Exec("SET enable_external_access = false"); err != nil {
+		return nil, fmt.Errorf("failed to restrict database access: %w", err)
+	}
+
+	return &Engine{db: database}, nil
+}
+
+func load(database *sql.DB, entries []*parser.ParsedEntry, sigs []signals.Signal) error {
+	tx, err := database.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin load: %w", err)
+	}
+	defer tx.Rollback()
+
+	insertEntry, err := tx.Prepare("INSERT INTO entries VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return fmt.Errorf("failed to prepare entries: %w", err)
+	}
+	insertToolUse, err := tx.Prepare("INSERT INTO tool_uses VALUES (?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return fmt.Errorf("failed to prepare tool_uses: %w", err)
+	}
+	insertToolResult, err := tx.Prepare("INSERT INTO tool_results VALUES (?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return fmt.Errorf("failed to prepare tool_results: %w", err)
+	}
+	insertSignal, err := tx.Prepare("INSERT INTO signals VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return fmt.Errorf("failed to prepare signals: %w", err)
+	}
+
+	for _, e := range entries {
+		text := e.UserContent
+		if e.Type == "assistant" {
+			text = assistantText(e)
+		}
+
+		if _, err := insertEntry.Exec(e.UUID, e.ParentUUID, e.SessionID, e.CWD, e.Type, e.Timestamp.UTC(), text, e.SourceFile, e.SourceLine); err != nil {
+			return fmt.Errorf("failed to load entry %s: %w", e.UUID, err)
+		}
+
+		for _, tool := range e.GetToolUses() {
+			input := string(tool.Input)
+			if input == "" {
+				input = "null"
+			}
+			if _, err := insertToolUse.Exec(tool.ID, e.UUID, e.SessionID, e.CWD, e.Timestamp.UTC(), tool.Name, input); err != nil {
+				return fmt.Errorf("failed to load tool use %s: %w", tool.ID, err)
+			}
+		}
+
+		for _, result := range e.GetToolResults() {
+			if _, err := insertToolResult.Exec(result.ToolUseID, e.UUID, e.SessionID, e.CWD, e.Timestamp.UTC(), result.IsError, result.ResultText()); err != nil {
+				return fmt.Errorf("failed to load tool result %s: %w", result.ToolUseID, err)
+			}
+		}
+	}
+
+	for _, s := range sigs {
+		if _, err := insertSignal.Exec(string(s.Type), s.Category, s.Key, s.Value, int(s.Strength), s.Timestamp.UTC(), s.SessionID, s.Project, s.MessageUUID, s.Context); err != nil {
+			return fmt.Errorf("failed to load signal: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit load: %w", err)
+	}
+
+	return nil
+}
+
+func assistantText(e *parser.ParsedEntry) string {
+	var parts []string
+	for _, block := range e.AssistantContent {
+		if block.Type == "text" {
+			parts = append(parts, block.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+type Result struct {
+	Columns []string
+	Rows    [][]any
+}
+
+var (
+	leadingComments = regexp.MustCompile(`^(\s*(--[^\n]*\n|/\*(?s:.*?)\*/))*\s*`)
+	explainAnalyze  = regexp.MustCompile(`(?i)^explain\s+analy[sz]e\b`)
+)
+
+// Run executes a single read-only statement and returns all of its rows.
+func (e *Engine) Run(query string) (*Result, error) {
+	query = strings.TrimSpace(query)
+	query = strings.TrimSpace(strings.TrimSuffix(query, ";"))
+	if query == "" {
+		return nil, fmt.Errorf("empty query")
+	}
+
+	if multipleStatements(query) {
+		return nil, fmt.Errorf("only one statement can be run at a time")
+	}
+
+	ctx := context.Background()
+	conn, err := e.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if err := checkReadOnly(ctx, conn, query); err != nil {
+		return nil, err
+	}
+
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns: %w", err)
+	}
+
+	result := &Result{Columns: columns}
+	for rows.Next() {
+		values := make([]any, len(columns))
+		ptrs := make([]any, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, fmt.Errorf("failed to read row: %w", err)
+		}
+		result.Rows = append(result.Rows, values)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return result, nil
+}
+
+// checkReadOnly lets through only statements DuckDB parses as queries, which
+// covers SELECT, WITH, DESCRIBE, SHOW, SUMMARIZE and PIVOT, and EXPLAIN
+// without ANALYZE, which plans a statement without running it.
+func checkReadOnly(ctx context.Context, conn *sql.Conn, query string) error {
+	var stmtType duckdb.StmtType
+	err := conn.Raw(func(driverConn any) error {
+		prepared, err := driverConn.(*duckdb.Conn).PrepareContext(ctx, query)
+		if err != nil {
+			return err
+		}
+		defer prepared.Close()
+
+		stmtType, err = prepared.(*duckdb.Stmt).StatementType()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	switch stmtType {
+	case duckdb.STATEMENT_TYPE_SELECT:
+		return nil
+	case duckdb.STATEMENT_TYPE_EXPLAIN:
+		if !explainAnalyze.MatchString(leadingComments.ReplaceAllString(query, "")) {
+			return nil
+		}
+	}
+	return fmt.Errorf("only read-only queries are allowed (SELECT, WITH, DESCRIBE, SHOW, SUMMARIZE, EXPLAIN)")
+}
+
+// multipleStatements reports whether a semicolon separates statements,
+// ignoring semicolons inside string literals, quoted identifiers and comments.
+func multipleStatements(query string) bool {
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return false
+			}
+			i += end + 3
+		case c == ';':
+			return true
+		}
+	}
+	return false
+}
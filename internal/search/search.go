@@ -0,0 +1,172 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/strrl/auto-flavor/internal/parser"
+)
+
+type Options struct {
+	Tool       string
+	SnippetLen int
+	Limit      int
+}
+
+type Match struct {
+	Entry   *parser.ParsedEntry
+	Field   string
+	Snippet string
+	Score   float64
+}
+
+type document struct {
+	entry *parser.ParsedEntry
+	field string
+	text  string
+	lower string
+}
+
+// Search ranks the text fields of entries against terms. Every field holding
+// user text, assistant text or a tool input is indexed separately and must
+// contain all terms to match; fields are scored with BM25 so that short,
+// focused messages rank above long ones that mention a term in passing.
+func Search(entries []*parser.ParsedEntry, terms []string, opts Options) []Match {
+	docs := collectDocuments(entries, opts.Tool)
+	if len(docs) == 0 || len(terms) == 0 {
+		return nil
+	}
+
+	lowerTerms := make([]string, len(terms))
+	for i, term := range terms {
+		lowerTerms[i] = strings.ToLower(term)
+	}
+
+	docFreq := make([]int, len(lowerTerms))
+	totalLen := 0
+	for _, doc := range docs {
+		totalLen += len(doc.lower)
+		for i, term := range lowerTerms {
+			if strings.Contains(doc.lower, term) {
+				docFreq[i]++
+			}
+		}
+	}
+	avgLen := float64(totalLen) / float64(len(docs))
+
+	const k1, b = 1.2, 0.75
+
+	var matches []Match
+	for _, doc := range docs {
+		score := 0.0
+		matched := true
+
+		for i, term := range lowerTerms {
+			tf := float64(strings.Count(doc.lower, term))
+			if tf == 0 {
+				matched = false
+				break
+			}
+
+			idf := math.Log(1 + (float64(len(docs))-float64(docFreq[i])+0.5)/(float64(docFreq[i])+0.5))
+			norm := 1 - b + b*float64(len(doc.lower))/avgLen
+			score += idf * tf * (k1 + 1) / (tf + k1*norm)
+		}
+
+		if !matched {
+			continue
+		}
+
+		matches = append(matches, Match{
+			Entry:   doc.entry,
+			Field:   doc.field,
+			Snippet: snippet(doc.text, doc.lower, lowerTerms[0], opts.SnippetLen),
+			Score:   score,
+		})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Entry.Timestamp.After(matches[j].Entry.Timestamp)
+	})
+
+	if opts.Limit > 0 && len(matches) > opts.Limit {
+		matches = matches[:opts.Limit]
+	}
+
+	return matches
+}
+
+func collectDocuments(entries []*parser.ParsedEntry, tool string) []document {
+	var docs []document
+
+	add := func(entry *parser.ParsedEntry, field, text string) {
+		if strings.TrimSpace(text) == "" {
+			return
+		}
+		docs = append(docs, document{entry: entry, field: field, text: text, lower: strings.ToLower(text)})
+	}
+
+	for _, entry := range entries {
+		switch entry.Type {
+		case "user":
+			if tool == "" {
+				add(entry, "user", entry.UserContent)
+			}
+		case "assistant":
+			for _, block := range entry.AssistantContent {
+				switch block.Type {
+				case "text":
+					if tool == "" {
+						add(entry, "assistant", block.Text)
+					}
+				case "tool_use":
+					if tool == "" || strings.EqualFold(block.Name, tool) {
+						add(entry, "tool:"+block.Name, string(block.Input))
+					}
+				}
+			}
+		}
+	}
+
+	return docs
+}
+
+func snippet(text, lower, term string, maxLen int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	lower = strings.Join(strings.Fields(lower), " ")
+
+	if maxLen <= 0 || len(text) <= maxLen {
+		return text
+	}
+
+	start := strings.Index(lower, strings.Join(strings.Fields(term), " ")) - maxLen/3
+	if start < 0 || len(lower) != len(text) {
+		start = 0
+	}
+	end := start + maxLen
+	if end > len(text) {
+		end = len(text)
+		start = max(0, end-maxLen)
+	}
+
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start--
+	}
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end++
+	}
+
+	result := text[start:end]
+	if start > 0 {
+		result = "..." + result
+	}
+	if end < len(text) {
+		result += "..."
+	}
+	return result
+}
@@ -2,49 +2,46 @@ package signals
 
 import (
 	"encoding/json"
+	"fmt"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/strrl/auto-flavor/internal/parser"
 )
 
-type Detector struct {
-	approvalPatterns   []*approvalPattern
-	correctionPatterns []*correctionPattern
-	extensionToLang    map[string]string
-	commandToTool      map[string]string
-}
+type Config struct {
+	ContextMaxLength int               `yaml:"context_max_length"`
+	KeyMaxLength     int               `yaml:"key_max_length"`
+	ExtensionToLang  map[string]string `yaml:"extension_to_lang"`
+	CommandToTool    map[string]string `yaml:"command_to_tool"`
 
-type approvalPattern struct {
-	Pattern  *regexp.Regexp
-	Strength SignalStrength
-}
+	// ToolChoices groups tools that fill the same role, such as npm and pnpm
+	// as package manager, so that switching from one to the other is seen as
+	// one setting changing.
+	ToolChoices map[string]string `yaml:"tool_choices"`
 
-type correctionPattern struct {
-	Pattern  *regexp.Regexp
-	Strength SignalStrength
-	Category string
+	// ScopeTurns is how many turns before and after a user message are
+	// searched for the files it refers to.
+	ScopeTurns int `yaml:"scope_turns"`
+
+	Quality QualityConfig `yaml:"quality"`
+
+	// DetectKinds lists the kinds of user entries that approvals,
+	// corrections and style rules are detected in. Slash commands and hooks
+	// are counted as stack signals whatever their kind.
+	DetectKinds []string `yaml:"detect_kinds"`
 }
 
-func NewDetector() *Detector {
-	return &Detector{
-		approvalPatterns: []*approvalPattern{
-			{regexp.MustCompile(`(?i)^(good|great|nice|perfect|excellent|awesome|lgtm|looks good)[\s!.]*$`), StrengthStrong},
-			{regexp.MustCompile(`(?i)^(thanks|thx|thank you|ty)[\s!.]*$`), StrengthModerate},
-			{regexp.MustCompile(`(?i)^(ok|okay|sure|yes|yep|yeah)[\s!.]*$`), StrengthWeak},
-			{regexp.MustCompile(`(?i)(good|great|nice) (job|work)`), StrengthStrong},
-		},
-		correctionPatterns: []*correctionPattern{
-			{regexp.MustCompile(`(?i)^no[,.]?\s+(.+)`), StrengthStrong, "rejection"},
-			{regexp.MustCompile(`(?i)(don't|do not|shouldn't|should not|never)\s+(.+)`), StrengthExplicit, "prohibition"},
-			{regexp.MustCompile(`(?i)use\s+(.+)\s+instead\s+of\s+(.+)`), StrengthExplicit, "preference"},
-			{regexp.MustCompile(`(?i)prefer\s+(.+)\s+over\s+(.+)`), StrengthExplicit, "preference"},
-			{regexp.MustCompile(`(?i)actually[,.]?\s+(.+)`), StrengthStrong, "correction"},
-			{regexp.MustCompile(`(?i)^(fix|change|update|modify)\s+(.+)`), StrengthModerate, "correction"},
-			{regexp.MustCompile(`(?i)always\s+(.+)`), StrengthExplicit, "requirement"},
-		},
-		extensionToLang: map[string]string{
+func DefaultConfig() Config {
+	return Config{
+		ContextMaxLength: 200,
+		KeyMaxLength:     50,
+		ScopeTurns:       1,
+		Quality:          DefaultQualityConfig(),
+		DetectKinds:      []string{string(parser.KindPrompt)},
+		ExtensionToLang: map[string]string{
 			".go":    "Go",
 			".ts":    "TypeScript",
 			".tsx":   "TypeScript/React",
@@ -67,35 +64,124 @@ func NewDetector() *Detector {
 			".json":  "JSON",
 			".md":    "Markdown",
 		},
-		commandToTool: map[string]string{
-			"npm":    "npm",
-			"yarn":   "yarn",
-			"pnpm":   "pnpm",
-			"go":     "go",
-			"cargo":  "cargo",
-			"pip":    "pip",
-			"poetry": "poetry",
-			"maven":  "maven",
-			"gradle": "gradle",
-			"make":   "make",
-			"docker": "docker",
-			"git":    "git",
+		CommandToTool: map[string]string{
+			"npm":     "npm",
+			"yarn":    "yarn",
+			"pnpm":    "pnpm",
+			"go":      "go",
+			"cargo":   "cargo",
+			"pip":     "pip",
+			"poetry":  "poetry",
+			"maven":   "maven",
+			"gradle":  "gradle",
+			"make":    "make",
+			"docker":  "docker",
+			"git":     "git",
 			"kubectl": "kubectl",
+			"bun":     "bun",
+			"uv":      "uv",
+			"jest":    "jest",
+			"vitest":  "vitest",
+			"mocha":   "mocha",
+		},
+		ToolChoices: map[string]string{
+			"npm":    "package_manager",
+			"yarn":   "package_manager",
+			"pnpm":   "package_manager",
+			"bun":    "package_manager",
+			"pip":    "python_package_manager",
+			"poetry": "python_package_manager",
+			"uv":     "python_package_manager",
+			"maven":  "jvm_build",
+			"gradle": "jvm_build",
+			"jest":   "js_test_runner",
+			"vitest": "js_test_runner",
+			"mocha":  "js_test_runner",
+		},
+	}
+}
+
+func (c Config) Validate() error {
+	for _, kind := range c.DetectKinds {
+		if !slices.Contains(parser.EntryKinds(), parser.EntryKind(kind)) {
+			return fmt.Errorf("unknown entry kind %q in detect_kinds", kind)
+		}
+	}
+	return nil
+}
+
+type Detector struct {
+	config             Config
+	approvalPatterns   []*approvalPattern
+	correctionPatterns []*correctionPattern
+	extensionToLang    map[string]string
+	commandToTool      map[string]string
+	roots              map[string]string
+}
+
+type approvalPattern struct {
+	Pattern  *regexp.Regexp
+	Strength SignalStrength
+}
+
+// correctionPattern matches a correction. KeyGroup is the submatch holding
+// what the correction is about, which becomes the key of its signal.
+type correctionPattern struct {
+	Pattern  *regexp.Regexp
+	Strength SignalStrength
+	Category string
+	KeyGroup int
+}
+
+func NewDetector(cfg Config) *Detector {
+	return &Detector{
+		config: cfg,
+		approvalPatterns: []*approvalPattern{
+			{regexp.MustCompile(`(?i)^(good|great|nice|perfect|excellent|awesome|lgtm|looks good)[\s!.]*$`), StrengthStrong},
+			{regexp.MustCompile(`(?i)^(thanks|thx|thank you|ty)[\s!.]*$`), StrengthModerate},
+			{regexp.MustCompile(`(?i)^(ok|okay|sure|yes|yep|yeah)[\s!.]*$`), StrengthWeak},
+			{regexp.MustCompile(`(?i)(good|great|nice) (job|work)`), StrengthStrong},
 		},
+		correctionPatterns: []*correctionPattern{
+			{regexp.MustCompile(`(?i)^no[,.]?\s+(.+)`), StrengthStrong, "rejection", 1},
+			{regexp.MustCompile(`(?i)(don't|do not|shouldn't|should not|never)\s+(.+)`), StrengthExplicit, "prohibition", 2},
+			{regexp.MustCompile(`(?i)use\s+(.+)\s+instead\s+of\s+(.+)`), StrengthExplicit, "preference", 1},
+			{regexp.MustCompile(`(?i)prefer\s+(.+)\s+over\s+(.+)`), StrengthExplicit, "preference", 1},
+			{regexp.MustCompile(`(?i)actually[,.]?\s+(.+)`), StrengthStrong, "correction", 1},
+			{regexp.MustCompile(`(?i)^(fix|change|update|modify)\s+(.+)`), StrengthModerate, "correction", 2},
+			{regexp.MustCompile(`(?i)always\s+(.+)`), StrengthExplicit, "requirement", 1},
+		},
+		extensionToLang: cfg.ExtensionToLang,
+		commandToTool:   cfg.CommandToTool,
+		roots:           make(map[string]string),
 	}
 }
 
 func (d *Detector) DetectSignals(entries []*parser.ParsedEntry) []Signal {
 	var signals []Signal
+	turns := d.newTurnIndex(entries)
 
 	for i, entry := range entries {
-		if entry.Type == "user" && entry.UserContent != "" {
+		// The user turns of a subagent's conversation are the parent
+		// agent's prompts, not the user's.
+		if entry.Type == "user" && entry.UserContent != "" && !entry.Sidechain {
+			if sig, ok := d.detectCommandUse(entry); ok {
+				signals = append(signals, sig)
+			}
+			if !d.detectsKind(entry.Kind) {
+				continue
+			}
+
 			var prevAssistant *parser.ParsedEntry
-			if i > 0 && entries[i-1].Type == "assistant" {
+			if i > 0 && entries[i-1].Type == "assistant" && entries[i-1].SessionID == entry.SessionID && !entries[i-1].Sidechain {
 				prevAssistant = entries[i-1]
 			}
 
-			signals = append(signals, d.detectFromUserMessage(entry, prevAssistant)...)
+			files := turns.filesAround(i, d.config.ScopeTurns)
+			for _, sig := range d.detectFromUserMessage(entry, prevAssistant) {
+				sig.Files = files
+				signals = append(signals, sig)
+			}
 		}
 
 		if entry.Type == "assistant" {
@@ -109,17 +195,26 @@ func (d *Detector) DetectSignals(entries []*parser.ParsedEntry) []Signal {
 func (d *Detector) detectFromUserMessage(entry *parser.ParsedEntry, prevAssistant *parser.ParsedEntry) []Signal {
 	var signals []Signal
 	content := strings.TrimSpace(entry.UserContent)
+	masked := maskNonProse(content)
+	if strings.TrimSpace(masked) == "" {
+		return nil
+	}
 
 	for _, pattern := range d.approvalPatterns {
-		if pattern.Pattern.MatchString(content) {
+		if pattern.Pattern.MatchString(strings.TrimSpace(masked)) {
 			sig := Signal{
-				Type:      SignalApproval,
-				Category:  "approval",
-				Key:       "user_approval",
-				Value:     content,
-				Strength:  pattern.Strength,
-				Timestamp: entry.Timestamp,
-				Context:   d.getAssistantContext(prevAssistant),
+				Type:        SignalApproval,
+				Category:    "approval",
+				Key:         "user_approval",
+				Value:       content,
+				Strength:    pattern.Strength,
+				Timestamp:   entry.Timestamp,
+				SessionID:   entry.SessionID,
+				Project:     entry.CWD,
+				MessageUUID: entry.UUID,
+				SourceFile:  entry.SourceFile,
+				SourceLine:  entry.SourceLine,
+				Context:     d.getAssistantContext(prevAssistant),
 			}
 			signals = append(signals, sig)
 			break
@@ -127,28 +222,95 @@ func (d *Detector) detectFromUserMessage(entry *parser.ParsedEntry, prevAssistan
 	}
 
 	for _, pattern := range d.correctionPatterns {
-		if matches := pattern.Pattern.FindStringSubmatch(content); matches != nil {
-			sig := Signal{
-				Type:      SignalCorrection,
-				Category:  pattern.Category,
-				Key:       d.extractCorrectionKey(matches),
-				Value:     content,
-				Strength:  pattern.Strength,
-				Timestamp: entry.Timestamp,
-				Context:   d.getAssistantContext(prevAssistant),
+		keys := make(map[string]bool)
+		for _, loc := range correctionMatches(pattern, masked) {
+			key := d.extractCorrectionKey(submatches(content, loc), pattern.KeyGroup)
+			if keys[key] {
+				continue
 			}
-			signals = append(signals, sig)
+			keys[key] = true
+
+			signals = append(signals, Signal{
+				Type:        SignalCorrection,
+				Category:    pattern.Category,
+				Key:         key,
+				Value:       content,
+				Strength:    pattern.Strength,
+				Timestamp:   entry.Timestamp,
+				SessionID:   entry.SessionID,
+				Project:     entry.CWD,
+				MessageUUID: entry.UUID,
+				SourceFile:  entry.SourceFile,
+				SourceLine:  entry.SourceLine,
+				Context:     d.getAssistantContext(prevAssistant),
+			})
 		}
 	}
 
-	signals = append(signals, d.detectExplicitStyleRules(entry)...)
+	signals = append(signals, d.detectExplicitStyleRules(entry, masked)...)
 
 	return signals
 }
 
-func (d *Detector) detectExplicitStyleRules(entry *parser.ParsedEntry) []Signal {
+// correctionMatches returns where a correction pattern matches the masked
+// message. A message can hold several prohibitions, and a negated one can be
+// followed by one that stands, so every negation is tried in turn.
+func correctionMatches(pattern *correctionPattern, masked string) [][]int {
+	if pattern.Category != "prohibition" {
+		if loc := findInProse(pattern.Pattern, masked); loc != nil {
+			return [][]int{loc}
+		}
+		return nil
+	}
+
+	var locs [][]int
+	for pos := 0; pos < len(masked); {
+		loc := pattern.Pattern.FindStringSubmatchIndex(masked[pos:])
+		if loc == nil {
+			break
+		}
+		for i := range loc {
+			if loc[i] >= 0 {
+				loc[i] += pos
+			}
+		}
+
+		if !negatedProhibition(masked, loc[2], loc[3]) {
+			locs = append(locs, loc)
+		}
+		pos = loc[3]
+	}
+	return locs
+}
+
+// findInProse matches pattern against a masked message as if the masked
+// text before the user's first words was not there, so that anchored
+// patterns still apply, and returns submatch indices into the message.
+func findInProse(pattern *regexp.Regexp, masked string) []int {
+	lead := len(masked) - len(strings.TrimLeft(masked, " \t\n"))
+
+	loc := pattern.FindStringSubmatchIndex(masked[lead:])
+	for i := range loc {
+		if loc[i] >= 0 {
+			loc[i] += lead
+		}
+	}
+	return loc
+}
+
+func submatches(text string, loc []int) []string {
+	matches := make([]string, len(loc)/2)
+	for i := range matches {
+		if loc[2*i] >= 0 {
+			matches[i] = text[loc[2*i]:loc[2*i+1]]
+		}
+	}
+	return matches
+}
+
+func (d *Detector) detectExplicitStyleRules(entry *parser.ParsedEntry, masked string) []Signal {
 	var signals []Signal
-	content := strings.ToLower(entry.UserContent)
+	content := strings.ToLower(masked)
 
 	stylePatterns := []struct {
 		pattern     string
@@ -157,8 +319,8 @@ func (d *Detector) detectExplicitStyleRules(entry *parser.ParsedEntry) []Signal
 	}{
 		{`no.*(end[- ]?of[- ]?line|inline).*(comment|//|#)`, "no_end_of_line_comments", "No end-of-line comments"},
 		{`no.*(chinese|mandarin).*(comment|code)`, "no_chinese_comments", "No Chinese in comments"},
-		{`use.*(camel\s*case|camelCase)`, "naming_camelCase", "Use camelCase naming"},
-		{`use.*(snake[_ ]case|snake_case)`, "naming_snake_case", "Use snake_case naming"},
+		{`use.*(camel\s*case|camelCase)`, "naming", "Use camelCase naming"},
+		{`use.*(snake[_ ]case|snake_case)`, "naming", "Use snake_case naming"},
 		{`prefer.*explicit.*error`, "explicit_error_handling", "Prefer explicit error handling"},
 		{`no.*emoji`, "no_emoji", "No emojis in code"},
 	}
@@ -166,13 +328,18 @@ func (d *Detector) detectExplicitStyleRules(entry *parser.ParsedEntry) []Signal
 	for _, sp := range stylePatterns {
 		if matched, _ := regexp.MatchString(sp.pattern, content); matched {
 			signals = append(signals, Signal{
-				Type:      SignalStyle,
-				Category:  "explicit_style",
-				Key:       sp.key,
-				Value:     sp.description,
-				Strength:  StrengthExplicit,
-				Timestamp: entry.Timestamp,
-				Context:   entry.UserContent,
+				Type:        SignalStyle,
+				Category:    "explicit_style",
+				Key:         sp.key,
+				Value:       sp.description,
+				Strength:    StrengthExplicit,
+				Timestamp:   entry.Timestamp,
+				SessionID:   entry.SessionID,
+				Project:     entry.CWD,
+				MessageUUID: entry.UUID,
+				SourceFile:  entry.SourceFile,
+				SourceLine:  entry.SourceLine,
+				Context:     entry.UserContent,
 			})
 		}
 	}
@@ -208,12 +375,17 @@ func (d *Detector) detectStackFromFile(tool parser.ContentBlock, entry *parser.P
 	ext := filepath.Ext(input.FilePath)
 	if lang, ok := d.extensionToLang[ext]; ok {
 		signals = append(signals, Signal{
-			Type:      SignalStack,
-			Category:  "language",
-			Key:       lang,
-			Value:     input.FilePath,
-			Strength:  StrengthWeak,
-			Timestamp: entry.Timestamp,
+			Type:        SignalStack,
+			Category:    "language",
+			Key:         lang,
+			Value:       input.FilePath,
+			Strength:    StrengthWeak,
+			Timestamp:   entry.Timestamp,
+			SessionID:   entry.SessionID,
+			Project:     entry.CWD,
+			MessageUUID: entry.UUID,
+			SourceFile:  entry.SourceFile,
+			SourceLine:  entry.SourceLine,
 		})
 	}
 
@@ -235,29 +407,100 @@ func (d *Detector) detectStackFromCommand(tool parser.ContentBlock, entry *parse
 		return signals
 	}
 
-	cmd := parts[0]
-	if toolName, ok := d.commandToTool[cmd]; ok {
-		signals = append(signals, Signal{
-			Type:      SignalStack,
-			Category:  "tool",
-			Key:       toolName,
-			Value:     input.Command,
-			Strength:  StrengthWeak,
-			Timestamp: entry.Timestamp,
-		})
+	// Package runners start the tool named after them.
+	cmds := parts[:1]
+	if slices.Contains(packageRunners, parts[0]) && len(parts) > 1 {
+		cmds = parts[:2]
+	}
+
+	for _, cmd := range cmds {
+		toolName, ok := d.commandToTool[cmd]
+		if !ok {
+			continue
+		}
+
+		sig := Signal{
+			Type:        SignalStack,
+			Category:    "tool",
+			Key:         toolName,
+			Value:       input.Command,
+			Strength:    StrengthWeak,
+			Timestamp:   entry.Timestamp,
+			SessionID:   entry.SessionID,
+			Project:     entry.CWD,
+			MessageUUID: entry.UUID,
+			SourceFile:  entry.SourceFile,
+			SourceLine:  entry.SourceLine,
+		}
+		signals = append(signals, sig)
+
+		if role, ok := d.config.ToolChoices[toolName]; ok {
+			sig.Category = "choice"
+			sig.Key = role
+			sig.Value = toolName
+			signals = append(signals, sig)
+		}
 	}
 
 	return signals
 }
 
+var packageRunners = []string{"npx", "pnpx", "bunx"}
+
+// detectsKind reports whether preferences are detected in user entries of
+// kind. Entries that were never classified count as prompts.
+func (d *Detector) detectsKind(kind parser.EntryKind) bool {
+	if kind == "" {
+		kind = parser.KindPrompt
+	}
+	return slices.Contains(d.config.DetectKinds, string(kind))
+}
+
+// detectCommandUse records a slash command invocation or a hook message as
+// a usage signal keyed and valued by its name, so that the commands and hooks
+// a team relies on show up next to its toolchain. The arguments or hook text
+// are kept as context only.
+func (d *Detector) detectCommandUse(entry *parser.ParsedEntry) (Signal, bool) {
+	var category, context string
+	switch entry.Kind {
+	case parser.KindSlashCommand:
+		category = "slash_command"
+		context = strings.TrimSpace(entry.Command + " " + entry.CommandArgs())
+	case parser.KindHook:
+		category = "hook"
+		context, _, _ = strings.Cut(strings.TrimSpace(entry.UserContent), "\n")
+	default:
+		return Signal{}, false
+	}
+
+	if len(context) > d.config.ContextMaxLength {
+		context = context[:d.config.ContextMaxLength] + "..."
+	}
+
+	return Signal{
+		Type:        SignalUsage,
+		Category:    category,
+		Key:         entry.Command,
+		Value:       entry.Command,
+		Context:     context,
+		Strength:    StrengthWeak,
+		Timestamp:   entry.Timestamp,
+		SessionID:   entry.SessionID,
+		Project:     entry.CWD,
+		MessageUUID: entry.UUID,
+		SourceFile:  entry.SourceFile,
+		SourceLine:  entry.SourceLine,
+	}, true
+}
+
 func (d *Detector) getAssistantContext(entry *parser.ParsedEntry) string {
 	if entry == nil {
 		return ""
 	}
 
 	if text := entry.GetTextContent(); text != "" {
-		if len(text) > 200 {
-			return text[:200] + "..."
+		if len(text) > d.config.ContextMaxLength {
+			return text[:d.config.ContextMaxLength] + "..."
 		}
 		return text
 	}
@@ -274,13 +517,24 @@ func (d *Detector) getAssistantContext(entry *parser.ParsedEntry) string {
 	return ""
 }
 
-func (d *Detector) extractCorrectionKey(matches []string) string {
-	if len(matches) > 1 {
-		key := strings.TrimSpace(matches[1])
-		if len(key) > 50 {
-			key = key[:50]
-		}
-		return key
+// extractCorrectionKey returns the first clause of the submatch a
+// correction is about, so that "don't use mocks, use fakes" and "don't use
+// mocks." share the key "use mocks" while other prohibitions do not.
+func (d *Detector) extractCorrectionKey(matches []string, group int) string {
+	if group <= 0 || group >= len(matches) {
+		return "unknown"
+	}
+
+	key := matches[group]
+	if loc := sentenceBoundary.FindStringIndex(key); loc != nil {
+		key = key[:loc[0]]
+	}
+	key = strings.Join(strings.Fields(key), " ")
+	if len(key) > d.config.KeyMaxLength {
+		key = key[:d.config.KeyMaxLength]
+	}
+	if key == "" {
+		return "unknown"
 	}
-	return "unknown"
+	return key
 }
@@ -0,0 +1,126 @@
+package signals
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	fenceLine  = regexp.MustCompile("^\\s*(```|~~~)")
+	quoteLine  = regexp.MustCompile(`^\s*>`)
+	inlineCode = regexp.MustCompile("`[^`\n]+`")
+
+	// Indented lines are only taken for pasted code when they read like code,
+	// so that indented list items and wrapped prose stay prose.
+	indentedLine = regexp.MustCompile(`^(\t| {4})`)
+	listItem     = regexp.MustCompile(`^\s*([-*+•]|\d+[.)])\s`)
+	codeLike     = regexp.MustCompile(`^\s*(func|def|class|return|import|from|package|if|for|while|const|let|var|public|private|static|#include)\b|[{};]\s*$|\)\s*$|=>|->|::|\s[!=]?==?\s|\w\([^)]*\)`)
+
+	pastedLines = []*regexp.Regexp{
+		regexp.MustCompile(`^\s*at\s+[\w$.<>\[\]]+\s*\(`),
+		regexp.MustCompile(`^\s*File "[^"]+", line \d+`),
+		regexp.MustCompile(`^\s*Traceback \(most recent call last\)`),
+		regexp.MustCompile(`^\s*goroutine \d+ \[`),
+		regexp.MustCompile(`^\s*[\w./-]+\.\w+:\d+`),
+		regexp.MustCompile(`(?i)^\s*(panic|fatal|error|warning|exception|npm ERR!|\w+(Error|Exception))(:|!|\s*\[)`),
+		regexp.MustCompile(`^\s*\[?\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}`),
+		regexp.MustCompile(`^\s*\[?(INFO|WARN|WARNING|ERROR|DEBUG|TRACE|FATAL)\b`),
+		regexp.MustCompile(`^\s*\$ `),
+	}
+
+	injectedTags  = `(?:system-reminder|user-prompt-submit-hook|command-message|command-name|command-args|local-command-stdout|local-command-stderr)`
+	injectedBlock = regexp.MustCompile(`(?s)<` + injectedTags + `>.*?</` + injectedTags + `>`)
+
+	negator          = regexp.MustCompile(`(?i)\b(not|no|never|nobody|nothing)\b|n't\b`)
+	negatedReport    = regexp.MustCompile(`(?i)(\b(not|never)\b|n't)\s+(\w+\s+)?(saying|say|mean|meaning|meant|think|thinking|asking|ask|telling|tell|suggesting|suggest|claiming|that|sure)\b`)
+	sentenceBoundary = regexp.MustCompile(`[.,!?;\n]`)
+)
+
+// codeMark stands in for the bytes of inline code. Unlike a blank it keeps
+// the span a token of the sentence, so "use `pnpm` instead of `npm`" still
+// captures the code spans, while no pattern matches words inside them.
+const codeMark = '\x00'
+
+// maskNonProse blanks out everything in a message the user did not write as
+// prose: blocks injected by the agent or a hook, fenced and indented code,
+// quoted lines and pasted logs or stack traces, and marks inline code with
+// codeMark. Offsets are kept, so matches on the result index into the
+// original message.
+func maskNonProse(text string) string {
+	masked := []byte(text)
+	fill := func(start, end int, b byte) {
+		for i := start; i < end; i++ {
+			if masked[i] != '\n' {
+				masked[i] = b
+			}
+		}
+	}
+	blank := func(start, end int) { fill(start, end, ' ') }
+
+	for _, loc := range injectedBlock.FindAllStringIndex(text, -1) {
+		blank(loc[0], loc[1])
+	}
+
+	inFence := false
+	offset := 0
+	for _, line := range strings.SplitAfter(string(masked), "\n") {
+		end := offset + len(line)
+
+		switch {
+		case fenceLine.MatchString(line):
+			inFence = !inFence
+			blank(offset, end)
+		case inFence, quoteLine.MatchString(line):
+			blank(offset, end)
+		case indentedLine.MatchString(line) && !listItem.MatchString(line) && codeLike.MatchString(line):
+			blank(offset, end)
+		default:
+			for _, pasted := range pastedLines {
+				if pasted.MatchString(line) {
+					blank(offset, end)
+					break
+				}
+			}
+		}
+
+		offset = end
+	}
+
+	for _, loc := range inlineCode.FindAllStringIndex(string(masked), -1) {
+		fill(loc[0], loc[1], codeMark)
+	}
+
+	return string(masked)
+}
+
+// removedNonProse reports whether masking blanked out text of a message, such
+// as a code block, a quote or a pasted log. Inline code spans are only marked
+// and do not count.
+func removedNonProse(text, masked string) bool {
+	for i := 0; i < len(text); i++ {
+		if masked[i] == ' ' && !unicode.IsSpace(rune(text[i])) {
+			return true
+		}
+	}
+	return false
+}
+
+// negatedProhibition reports whether a prohibition whose negation spans
+// [start, end) of masked is itself negated, either by a negated report earlier
+// in the sentence ("I'm not saying you shouldn't ...") or right after it
+// ("don't not ..."), which turns it into something other than a prohibition.
+// A plain negation earlier in the sentence, as in "this isn't right don't use
+// X", leaves the prohibition standing.
+func negatedProhibition(masked string, start, end int) bool {
+	sentenceStart := 0
+	if locs := sentenceBoundary.FindAllStringIndex(masked[:start], -1); len(locs) > 0 {
+		sentenceStart = locs[len(locs)-1][1]
+	}
+	if negatedReport.MatchString(masked[sentenceStart:start]) {
+		return true
+	}
+
+	next := strings.Fields(masked[end:])
+	return len(next) > 0 && negator.MatchString(next[0])
+}
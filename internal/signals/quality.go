@@ -0,0 +1,189 @@
+package signals
+
+import (
+	"regexp"
+	"strings"
+)
+
+// QualityConfig tunes the stage that tells durable preferences apart from
+// one-off task instructions among corrections. Corrections scoring below
+// DropBelow are discarded and those below DemoteBelow lose one step of
+// strength.
+type QualityConfig struct {
+	Enabled     bool    `yaml:"enabled"`
+	DropBelow   float64 `yaml:"drop_below"`
+	DemoteBelow float64 `yaml:"demote_below"`
+}
+
+func DefaultQualityConfig() QualityConfig {
+	return QualityConfig{
+		Enabled:     true,
+		DropBelow:   0.3,
+		DemoteBelow: 0.5,
+	}
+}
+
+// ClassifyStats counts what the quality stage did to corrections.
+type ClassifyStats struct {
+	Dropped int
+	Demoted int
+}
+
+type Classifier struct {
+	config QualityConfig
+}
+
+func NewClassifier(cfg QualityConfig) *Classifier {
+	return &Classifier{config: cfg}
+}
+
+var (
+	generalMarkers = regexp.MustCompile(`(?i)\b(always|never|prefer|avoid|from now on|whenever|every time|by default|in general|as a rule|instead of)\b`)
+	softMarkers    = regexp.MustCompile(`(?i)\b(don't|do not|shouldn't|should not)\b`)
+	ruleVerbs      = regexp.MustCompile(`(?i)^(?:no[,.]?\s+|actually[,.]?\s+)?(use|prefer|avoid|keep|write|name|put|stick|follow|stop)\b`)
+	taskVerbs      = regexp.MustCompile(`(?i)^(?:no[,.]?\s+|actually[,.]?\s+)?(fix|change|update|modify|add|implement|remove|delete|rename|move|create|make|run|debug|revert|try|check)\b`)
+	deictic        = regexp.MustCompile(`(?i)\b(this|that|these|those|here|above|below)\b`)
+	specificThing  = regexp.MustCompile(`(?i)\b(the|this|that|my|our)\s+(?:[\w-]+\s+)?(bug|issue|error|test|function|method|file|component|page|endpoint|button|query|script|job|ticket|pr)\b`)
+
+	filePath   = regexp.MustCompile(`(?:^|[\s(])(?:~|\.{1,2})?/[\w.-]+|\b[\w.-]+/[\w.-]+/[\w./-]*|\b[\w-]+(?:/[\w.-]+)*\.(?:go|ts|tsx|js|jsx|py|rs|java|kt|rb|php|cs|cpp|c|h|swift|sql|sh|yaml|yml|json|md|toml|css|html)\b`)
+	identifier = regexp.MustCompile(`\b[a-z]+[A-Z]\w*\b|\b[a-z]+_[a-z_]+\b|\b\w+\(\)`)
+	lineRef    = regexp.MustCompile(`(?i)\bline\s+\d+\b|\b[\w-]+\.\w+:\d+\b|#\d+\b`)
+	url        = regexp.MustCompile(`https?://\S+`)
+
+	// namingConventions name a style rather than a symbol in the code.
+	namingConventions = map[string]bool{
+		"camelcase": true, "pascalcase": true, "snake_case": true,
+		"screaming_snake_case": true, "kebab_case": true, "lowercase": true,
+	}
+)
+
+// Classify scores every correction for how well it generalizes beyond the
+// task at hand and drops or demotes the low scorers. Other signals pass
+// through unchanged.
+func (c *Classifier) Classify(sigs []Signal) ([]Signal, ClassifyStats) {
+	var stats ClassifyStats
+	if !c.config.Enabled {
+		return sigs, stats
+	}
+
+	recurrence := correctionRecurrence(sigs)
+
+	kept := make([]Signal, 0, len(sigs))
+	for _, sig := range sigs {
+		if sig.Type != SignalCorrection {
+			kept = append(kept, sig)
+			continue
+		}
+
+		score := c.Score(sig, recurrence[normalizeCorrection(sig.Value)])
+		switch {
+		case score < c.config.DropBelow:
+			stats.Dropped++
+			continue
+		case score < c.config.DemoteBelow && sig.Strength > StrengthWeak:
+			sig.Strength--
+			stats.Demoted++
+		}
+		kept = append(kept, sig)
+	}
+
+	return kept, stats
+}
+
+// Score rates a correction from 0, a one-off task instruction, to 1, a
+// durable preference. sessions is the number of sessions in which the same
+// correction was made. Markers and specifics are read from the prose only,
+// so quoted or pasted code and logs count once, as code, and not for every
+// path or identifier they contain.
+func (c *Classifier) Score(sig Signal, sessions int) float64 {
+	raw := strings.TrimSpace(sig.Value)
+	masked := maskNonProse(raw)
+	text := strings.TrimSpace(masked)
+	score := 0.5
+
+	switch sig.Category {
+	case "prohibition", "requirement", "preference":
+		score += 0.1
+	case "correction", "rejection":
+		score -= 0.1
+	}
+
+	if generalMarkers.MatchString(text) {
+		score += 0.3
+	} else if softMarkers.MatchString(text) {
+		score += 0.15
+	}
+
+	if ruleVerbs.MatchString(text) {
+		score += 0.1
+	} else if taskVerbs.MatchString(text) {
+		score -= 0.1
+	}
+
+	for _, specific := range []*regexp.Regexp{filePath, lineRef, url} {
+		if specific.MatchString(text) {
+			score -= 0.15
+		}
+	}
+	if hasIdentifier(text) {
+		score -= 0.15
+	}
+	if specificThing.MatchString(text) {
+		score -= 0.15
+	} else if deictic.MatchString(text) {
+		score -= 0.1
+	}
+
+	if removedNonProse(raw, masked) {
+		score -= 0.2
+	}
+	if strings.HasSuffix(text, "?") {
+		score -= 0.3
+	}
+	if len(raw) > 300 {
+		score -= 0.1
+	}
+
+	if sessions > 1 {
+		score += min(0.1*float64(sessions-1), 0.3)
+	}
+
+	return max(0, min(1, score))
+}
+
+func hasIdentifier(text string) bool {
+	for _, m := range identifier.FindAllString(text, -1) {
+		if !namingConventions[strings.ToLower(m)] {
+			return true
+		}
+	}
+	return false
+}
+
+// correctionRecurrence counts, per normalized correction text, the distinct
+// sessions it was made in.
+func correctionRecurrence(sigs []Signal) map[string]int {
+	sessions := make(map[string]map[string]struct{})
+	for _, sig := range sigs {
+		if sig.Type != SignalCorrection {
+			continue
+		}
+		key := normalizeCorrection(sig.Value)
+		if sessions[key] == nil {
+			sessions[key] = make(map[string]struct{})
+		}
+		sessions[key][sig.SessionID] = struct{}{}
+	}
+
+	counts := make(map[string]int, len(sessions))
+	for key, ids := range sessions {
+		counts[key] = len(ids)
+	}
+	return counts
+}
+
+var nonWordRun = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+func normalizeCorrection(s string) string {
+	return strings.TrimSpace(nonWordRun.ReplaceAllString(strings.ToLower(s), " "))
+}
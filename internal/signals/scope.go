@@ -0,0 +1,127 @@
+package signals
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/strrl/auto-flavor/internal/parser"
+)
+
+// turnIndex splits each session into turns, a user prompt followed by the
+// assistant's work on it, and records the files edited in every turn.
+// Subagents' conversations belong to the turn that started them.
+type turnIndex struct {
+	turnOf   []int
+	sessions []string
+	files    map[string][][]string
+}
+
+func (d *Detector) newTurnIndex(entries []*parser.ParsedEntry) *turnIndex {
+	idx := &turnIndex{
+		turnOf:   make([]int, len(entries)),
+		sessions: make([]string, len(entries)),
+		files:    make(map[string][][]string),
+	}
+
+	for i, entry := range entries {
+		turns := idx.files[entry.SessionID]
+		if len(turns) == 0 || (entry.Type == "user" && entry.UserContent != "" && !entry.Sidechain) {
+			turns = append(turns, nil)
+		}
+
+		t := len(turns) - 1
+		if entry.Type == "assistant" {
+			turns[t] = append(turns[t], d.editedFiles(entry)...)
+		}
+
+		idx.files[entry.SessionID] = turns
+		idx.turnOf[i] = t
+		idx.sessions[i] = entry.SessionID
+	}
+
+	return idx
+}
+
+// filesAround returns the files edited in the n turns before the turn of
+// entry i, which the user is likely reacting to, and in the n turns from it
+// on, which act on the user's message.
+func (idx *turnIndex) filesAround(i, n int) []string {
+	if n <= 0 {
+		return nil
+	}
+
+	turns := idx.files[idx.sessions[i]]
+	t := idx.turnOf[i]
+
+	seen := make(map[string]struct{})
+	var files []string
+	for j := max(t-n, 0); j < min(t+n, len(turns)); j++ {
+		for _, file := range turns[j] {
+			if _, ok := seen[file]; !ok {
+				seen[file] = struct{}{}
+				files = append(files, file)
+			}
+		}
+	}
+
+	sort.Strings(files)
+	return files
+}
+
+// editedFiles lists the files an assistant message writes to, relative to
+// the root of the project it ran in. Files outside the project are left out.
+func (d *Detector) editedFiles(entry *parser.ParsedEntry) []string {
+	var files []string
+
+	for _, tool := range entry.GetToolUses() {
+		switch tool.Name {
+		case "Write", "Edit", "MultiEdit":
+		default:
+			continue
+		}
+
+		var input struct {
+			FilePath string `json:"file_path"`
+		}
+		if err := json.Unmarshal(tool.Input, &input); err != nil || input.FilePath == "" {
+			continue
+		}
+
+		path := input.FilePath
+		if !filepath.IsAbs(path) {
+			if entry.CWD == "" {
+				continue
+			}
+			path = filepath.Join(entry.CWD, path)
+		}
+
+		rel, err := filepath.Rel(d.projectRoot(entry.CWD, path), path)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == ".." || strings.HasPrefix(rel, "../") {
+			continue
+		}
+		files = append(files, rel)
+	}
+
+	return files
+}
+
+// projectRoot resolves the repository root of cwd once per directory. Without
+// a working directory the file's own directory stands in.
+func (d *Detector) projectRoot(cwd, path string) string {
+	if cwd == "" {
+		return filepath.Dir(path)
+	}
+
+	root, ok := d.roots[cwd]
+	if !ok {
+		root = parser.ResolveProject(cwd).Root()
+		d.roots[cwd] = root
+	}
+	return root
+}
@@ -1,6 +1,8 @@
 package signals
 
 import (
+	"path"
+	"strings"
 	"time"
 )
 
@@ -11,6 +13,9 @@ const (
 	SignalCorrection SignalType = "correction"
 	SignalStack      SignalType = "stack"
 	SignalStyle      SignalType = "style"
+	// SignalUsage records a slash command or hook run. It feeds usage charts
+	// and queries but is not a preference.
+	SignalUsage SignalType = "usage"
 )
 
 type SignalStrength int
@@ -30,45 +35,132 @@ type Signal struct {
 	Strength  SignalStrength
 	Timestamp time.Time
 	Context   string
+	SessionID string
+	Project   string
+
+	MessageUUID string
+	SourceFile  string
+	SourceLine  int
+
+	// Files edited in the turns around the signal, relative to the root of
+	// the project and slash-separated.
+	Files []string
 }
 
 type Preference struct {
-	Category    string
-	Key         string
-	Value       string
-	Confidence  float64
-	SignalCount int
-	FirstSeen   time.Time
-	LastSeen    time.Time
+	Category    string              `json:"category"`
+	Key         string              `json:"key"`
+	Value       string              `json:"value"`
+	Confidence  float64             `json:"confidence"`
+	Breakdown   ConfidenceBreakdown `json:"breakdown"`
+	SignalCount int                 `json:"signal_count"`
+	FirstSeen   time.Time           `json:"first_seen"`
+	LastSeen    time.Time           `json:"last_seen"`
+
+	SessionCount int           `json:"session_count"`
+	SessionSpan  time.Duration `json:"session_span"`
+
+	Projects []string   `json:"projects,omitempty"`
+	Members  []string   `json:"members,omitempty"`
+	Evidence []Evidence `json:"evidence,omitempty"`
+
+	Scope *Scope `json:"scope,omitempty"`
+}
+
+// Scope narrows a preference to the files it was learned on: a directory
+// relative to the project root, file extensions, or both.
+type Scope struct {
+	Dir        string   `json:"dir,omitempty"`
+	Extensions []string `json:"extensions,omitempty"`
+}
+
+// Glob renders the scope as a glob relative to the project root, such as
+// "web/**", "web/**/*.tsx" or "**/*.{ts,tsx}".
+func (s Scope) Glob() string {
+	var files string
+	switch len(s.Extensions) {
+	case 0:
+	case 1:
+		files = "*" + s.Extensions[0]
+	default:
+		var exts []string
+		for _, ext := range s.Extensions {
+			exts = append(exts, strings.TrimPrefix(ext, "."))
+		}
+		files = "*.{" + strings.Join(exts, ",") + "}"
+	}
+
+	if s.Dir == "" {
+		return path.Join("**", files)
+	}
+	return path.Join(s.Dir, "**", files)
+}
+
+// Evidence is an excerpt from the conversation that produced a signal.
+type Evidence struct {
+	Text      string    `json:"text"`
+	Context   string    `json:"context,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	SessionID string    `json:"session_id,omitempty"`
+	Project   string    `json:"project,omitempty"`
+
+	MessageUUID string `json:"message_uuid,omitempty"`
+	SourceFile  string `json:"source_file,omitempty"`
+	SourceLine  int    `json:"source_line,omitempty"`
+}
+
+// ConfidenceBreakdown holds the 0..1 components that are weighted into a
+// preference's confidence.
+type ConfidenceBreakdown struct {
+	Recency       float64 `json:"recency"`
+	Frequency     float64 `json:"frequency"`
+	Strength      float64 `json:"strength"`
+	SessionSpread float64 `json:"session_spread"`
 }
 
 type ConflictingPreference struct {
-	Category string
-	Key      string
-	Values   []ConflictValue
+	Category string          `json:"category"`
+	Key      string          `json:"key"`
+	Values   []ConflictValue `json:"values,omitempty"`
+	Projects []string        `json:"projects,omitempty"`
 }
 
 type ConflictValue struct {
-	Value       string
-	Timestamp   time.Time
-	SignalCount int
-	Strength    float64
+	Value       string    `json:"value"`
+	Timestamp   time.Time `json:"timestamp"`
+	SignalCount int       `json:"signal_count"`
+	Strength    float64   `json:"strength"`
+	Members     []string  `json:"members,omitempty"`
+}
+
+type SupersededPreference struct {
+	Category    string    `json:"category"`
+	Key         string    `json:"key"`
+	OldValue    string    `json:"old_value"`
+	NewValue    string    `json:"new_value"`
+	SwitchedAt  time.Time `json:"switched_at"`
+	OldCount    int       `json:"old_count"`
+	NewCount    int       `json:"new_count"`
+	OldLastSeen time.Time `json:"old_last_seen"`
+	NewLastSeen time.Time `json:"new_last_seen"`
+	Projects    []string  `json:"projects,omitempty"`
 }
 
 type FlavorProfile struct {
-	CreatedAt        time.Time
-	AnalyzedMessages int
-	TimeRange        TimeRange
+	CreatedAt        time.Time `json:"created_at"`
+	AnalyzedMessages int       `json:"analyzed_messages"`
+	TimeRange        TimeRange `json:"time_range"`
 
-	StackPreferences []Preference
-	StylePreferences []Preference
-	Approvals        []Preference
-	Corrections      []Preference
+	StackPreferences []Preference `json:"stack_preferences,omitempty"`
+	StylePreferences []Preference `json:"style_preferences,omitempty"`
+	Approvals        []Preference `json:"approvals,omitempty"`
+	Corrections      []Preference `json:"corrections,omitempty"`
 
-	Conflicts []ConflictingPreference
+	Conflicts  []ConflictingPreference `json:"conflicts,omitempty"`
+	Superseded []SupersededPreference  `json:"superseded,omitempty"`
 }
 
 type TimeRange struct {
-	Start time.Time
-	End   time.Time
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
 }
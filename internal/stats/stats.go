@@ -0,0 +1,329 @@
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/strrl/auto-flavor/internal/parser"
+	"github.com/strrl/auto-flavor/internal/signals"
+)
+
+const (
+	PeriodNone  = ""
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+)
+
+type Options struct {
+	Period string
+	TopN   int
+
+	// SplitSubagents reports the turns and tool calls of subagents apart
+	// from the main agent's instead of adding them up.
+	SplitSubagents bool
+
+	// ProjectOf maps a recorded working directory to the project it is
+	// reported under. When nil, the directory itself is used.
+	ProjectOf func(cwd string) string
+}
+
+type ToolStat struct {
+	Name      string  `json:"name"`
+	Calls     int     `json:"calls"`
+	Errors    int     `json:"errors"`
+	ErrorRate float64 `json:"error_rate"`
+}
+
+type Count struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type Report struct {
+	Project string    `json:"project"`
+	Period  string    `json:"period,omitempty"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+
+	Sessions       int `json:"sessions"`
+	UserTurns      int `json:"user_turns"`
+	AssistantTurns int `json:"assistant_turns"`
+	ToolCalls      int `json:"tool_calls"`
+	ToolErrors     int `json:"tool_errors"`
+	Corrections    int `json:"corrections"`
+
+	AvgSessionMinutes     float64 `json:"avg_session_minutes"`
+	AvgTurnsPerSession    float64 `json:"avg_turns_per_session"`
+	CorrectionsPerSession float64 `json:"corrections_per_session"`
+
+	Tools    []ToolStat `json:"tools"`
+	Files    []Count    `json:"most_edited_files"`
+	Commands []Count    `json:"most_run_commands"`
+
+	SubagentTurns int        `json:"subagent_turns,omitempty"`
+	SubagentTools []ToolStat `json:"subagent_tools,omitempty"`
+}
+
+type bucket struct {
+	report   *Report
+	duration time.Duration
+	split    bool
+	tools    map[string]*ToolStat
+	subtools map[string]*ToolStat
+	files    map[string]int
+	commands map[string]int
+}
+
+// Compute summarizes how the assistant was used. Sessions are assigned to a
+// project and period by their first message, and everything that happens in
+// a session is counted there.
+func Compute(entries []*parser.ParsedEntry, sigs []signals.Signal, opts Options) []Report {
+	if opts.ProjectOf == nil {
+		opts.ProjectOf = func(cwd string) string { return cwd }
+	}
+
+	sessions := make(map[string][]*parser.ParsedEntry)
+	var order []string
+	for _, entry := range entries {
+		if _, ok := sessions[entry.SessionID]; !ok {
+			order = append(order, entry.SessionID)
+		}
+		sessions[entry.SessionID] = append(sessions[entry.SessionID], entry)
+	}
+
+	corrections := make(map[string]int)
+	for _, sig := range sigs {
+		if sig.Type == signals.SignalCorrection {
+			corrections[sig.SessionID]++
+		}
+	}
+
+	buckets := make(map[string]*bucket)
+	for _, id := range order {
+		session := sessions[id]
+		first, last := session[0], session[len(session)-1]
+
+		project := opts.ProjectOf(first.CWD)
+		period := periodLabel(first.Timestamp, opts.Period)
+		key := project + "\x00" + period
+
+		b, ok := buckets[key]
+		if !ok {
+			b = &bucket{
+				report:   &Report{Project: project, Period: period, Start: first.Timestamp},
+				split:    opts.SplitSubagents,
+				tools:    make(map[string]*ToolStat),
+				subtools: make(map[string]*ToolStat),
+				files:    make(map[string]int),
+				commands: make(map[string]int),
+			}
+			buckets[key] = b
+		}
+
+		b.report.Sessions++
+		b.report.Corrections += corrections[id]
+		b.duration += last.Timestamp.Sub(first.Timestamp)
+		if first.Timestamp.Before(b.report.Start) {
+			b.report.Start = first.Timestamp
+		}
+		if last.Timestamp.After(b.report.End) {
+			b.report.End = last.Timestamp
+		}
+
+		b.addSession(session)
+	}
+
+	reports := make([]Report, 0, len(buckets))
+	for _, b := range buckets {
+		reports = append(reports, b.finish(opts.TopN))
+	}
+
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].Project != reports[j].Project {
+			return reports[i].Project < reports[j].Project
+		}
+		return reports[i].Period < reports[j].Period
+	})
+
+	return reports
+}
+
+func (b *bucket) addSession(session []*parser.ParsedEntry) {
+	toolNames := make(map[string]string)
+
+	for _, entry := range session {
+		subagent := b.split && entry.Sidechain
+
+		switch entry.Type {
+		case "user":
+			if entry.Kind == parser.KindPrompt && strings.TrimSpace(entry.UserContent) != "" && !entry.Sidechain {
+				b.report.UserTurns++
+			}
+
+			for _, result := range entry.GetToolResults() {
+				if !result.IsError {
+					continue
+				}
+				if !subagent {
+					b.report.ToolErrors++
+				}
+				if name, ok := toolNames[result.ToolUseID]; ok {
+					b.tool(name, subagent).Errors++
+				}
+			}
+		case "assistant":
+			if subagent {
+				b.report.SubagentTurns++
+			} else {
+				b.report.AssistantTurns++
+			}
+
+			for _, tool := range entry.GetToolUses() {
+				toolNames[tool.ID] = tool.Name
+				if !subagent {
+					b.report.ToolCalls++
+				}
+				b.tool(tool.Name, subagent).Calls++
+
+				switch tool.Name {
+				case "Edit", "MultiEdit":
+					var input parser.EditToolInput
+					if json.Unmarshal(tool.Input, &input) == nil && input.FilePath != "" {
+						b.files[input.FilePath]++
+					}
+				case "Write":
+					var input parser.WriteToolInput
+					if json.Unmarshal(tool.Input, &input) == nil && input.FilePath != "" {
+						b.files[input.FilePath]++
+					}
+				case "Bash":
+					var input parser.BashToolInput
+					if json.Unmarshal(tool.Input, &input) == nil {
+						if name := commandName(input.Command); name != "" {
+							b.commands[name]++
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func (b *bucket) tool(name string, subagent bool) *ToolStat {
+	tools := b.tools
+	if subagent {
+		tools = b.subtools
+	}
+
+	stat, ok := tools[name]
+	if !ok {
+		stat = &ToolStat{Name: name}
+		tools[name] = stat
+	}
+	return stat
+}
+
+func (b *bucket) finish(topN int) Report {
+	r := *b.report
+
+	if r.Sessions > 0 {
+		n := float64(r.Sessions)
+		r.AvgSessionMinutes = b.duration.Minutes() / n
+		r.AvgTurnsPerSession = float64(r.UserTurns) / n
+		r.CorrectionsPerSession = float64(r.Corrections) / n
+	}
+
+	r.Tools = toolStats(b.tools)
+	r.SubagentTools = toolStats(b.subtools)
+
+	r.Files = topCounts(b.files, topN)
+	r.Commands = topCounts(b.commands, topN)
+
+	return r
+}
+
+func toolStats(tools map[string]*ToolStat) []ToolStat {
+	var result []ToolStat
+	for _, stat := range tools {
+		if stat.Calls > 0 {
+			stat.ErrorRate = float64(stat.Errors) / float64(stat.Calls)
+		}
+		result = append(result, *stat)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Calls != result[j].Calls {
+			return result[i].Calls > result[j].Calls
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+func topCounts(counts map[string]int, n int) []Count {
+	result := make([]Count, 0, len(counts))
+	for name, count := range counts {
+		result = append(result, Count{Name: name, Count: count})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Name < result[j].Name
+	})
+
+	if n > 0 && len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
+
+// commandName reduces a shell command to the program and, for tools that
+// are driven by subcommands, the subcommand: "go test ./..." becomes
+// "go test" while "ls -la" stays "ls".
+func commandName(command string) string {
+	fields := strings.Fields(command)
+	for len(fields) > 0 && (strings.Contains(fields[0], "=") || fields[0] == "sudo") {
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return ""
+	}
+
+	name := fields[0]
+	if len(fields) > 1 && subcommandTools[name] && !strings.HasPrefix(fields[1], "-") {
+		name += " " + fields[1]
+	}
+	return name
+}
+
+var subcommandTools = map[string]bool{
+	"bun": true, "cargo": true, "docker": true, "gh": true, "git": true,
+	"go": true, "kubectl": true, "make": true, "npm": true, "pip": true,
+	"pnpm": true, "poetry": true, "uv": true, "yarn": true,
+}
+
+func ValidatePeriod(period string) error {
+	switch period {
+	case PeriodNone, PeriodWeek, PeriodMonth:
+		return nil
+	default:
+		return fmt.Errorf("unknown period %q (want %s or %s)", period, PeriodWeek, PeriodMonth)
+	}
+}
+
+func periodLabel(t time.Time, period string) string {
+	switch period {
+	case PeriodWeek:
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case PeriodMonth:
+		return t.Format("2006-01")
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,388 @@
+package team
+
+import (
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/strrl/auto-flavor/internal/signals"
+)
+
+type Member struct {
+	Name    string
+	Profile *signals.FlavorProfile
+}
+
+type Outlier struct {
+	Member     string
+	Kind       string
+	Preference signals.Preference
+}
+
+// TeamProfile is the result of merging several developers' profiles. Profile
+// holds only what the team agrees on or needs to decide together; personal
+// outliers are kept separately so they never reach shared instruction files.
+type TeamProfile struct {
+	Members  []string
+	Quorum   float64
+	Profile  *signals.FlavorProfile
+	Outliers []Outlier
+}
+
+type memberPreference struct {
+	member string
+	pref   signals.Preference
+}
+
+// Merge combines member profiles. A rule held by at least quorum (a fraction
+// of members) becomes a team rule when those members agree on its value, and a
+// team conflict when they do not; only style rules can disagree, others agree
+// by holding the same key. Rules below quorum are personal outliers.
+// Members' own conflicts join the team conflicts unless the team agreed on a
+// rule for the same key, and their superseded records are combined.
+func Merge(members []Member, quorum float64) *TeamProfile {
+	result := &TeamProfile{
+		Quorum: quorum,
+		Profile: &signals.FlavorProfile{
+			CreatedAt: time.Now(),
+		},
+	}
+
+	for _, m := range members {
+		result.Members = append(result.Members, m.Name)
+		result.Profile.AnalyzedMessages += m.Profile.AnalyzedMessages
+		extendRange(&result.Profile.TimeRange, m.Profile.TimeRange)
+	}
+
+	required := requiredMembers(len(members), quorum)
+	agreed := make(map[string]bool)
+
+	kinds := []struct {
+		name   string
+		prefs  func(*signals.FlavorProfile) []signals.Preference
+		target *[]signals.Preference
+	}{
+		{"stack", func(p *signals.FlavorProfile) []signals.Preference { return p.StackPreferences }, &result.Profile.StackPreferences},
+		{"style", func(p *signals.FlavorProfile) []signals.Preference { return p.StylePreferences }, &result.Profile.StylePreferences},
+		{"correction", func(p *signals.FlavorProfile) []signals.Preference { return p.Corrections }, &result.Profile.Corrections},
+		{"approval", func(p *signals.FlavorProfile) []signals.Preference { return p.Approvals }, &result.Profile.Approvals},
+	}
+
+	for _, kind := range kinds {
+		groups := make(map[string][]memberPreference)
+		var order []string
+
+		for _, m := range members {
+			for _, pref := range kind.prefs(m.Profile) {
+				key := pref.Category + "::" + pref.Key
+				if _, ok := groups[key]; !ok {
+					order = append(order, key)
+				}
+				groups[key] = append(groups[key], memberPreference{member: m.Name, pref: pref})
+			}
+		}
+
+		for _, key := range order {
+			group := groups[key]
+
+			if len(distinctMembers(group)) < required {
+				for _, mp := range group {
+					result.Outliers = append(result.Outliers, Outlier{Member: mp.member, Kind: kind.name, Preference: mp.pref})
+				}
+				continue
+			}
+
+			byValue, values := groupByRule(kind.name, group)
+			if len(distinctMembers(byValue[values[0]])) >= required {
+				*kind.target = append(*kind.target, mergePreferences(byValue[values[0]]))
+				agreed[key] = true
+				for _, value := range values[1:] {
+					for _, mp := range byValue[value] {
+						result.Outliers = append(result.Outliers, Outlier{Member: mp.member, Kind: kind.name, Preference: mp.pref})
+					}
+				}
+				continue
+			}
+
+			result.Profile.Conflicts = append(result.Profile.Conflicts, buildConflict(group[0].pref, byValue, values))
+		}
+
+		sort.Slice(*kind.target, func(i, j int) bool {
+			return (*kind.target)[i].Confidence > (*kind.target)[j].Confidence
+		})
+	}
+
+	result.Profile.Conflicts = mergeConflicts(result.Profile.Conflicts, members, agreed)
+	result.Profile.Superseded = mergeSuperseded(members)
+
+	return result
+}
+
+func requiredMembers(total int, quorum float64) int {
+	required := int(float64(total)*quorum + 0.999999)
+	if required < 1 {
+		required = 1
+	}
+	return required
+}
+
+// groupByRule buckets member preferences by the rule they express. Stack
+// preferences carry an example file or command as their value, so the key
+// itself is the rule; for everything else the normalized value is compared.
+// Values are returned ordered by how many members hold them.
+func groupByRule(kind string, group []memberPreference) (map[string][]memberPreference, []string) {
+	byValue := make(map[string][]memberPreference)
+
+	for _, mp := range group {
+		// Only style values and tool choices are alternatives to one another;
+		// other values restate the key or hold the message it was learned
+		// from.
+		rule := normalizeRule(mp.pref.Key)
+		if kind == "style" || mp.pref.Category == "choice" {
+			rule = normalizeRule(mp.pref.Value)
+		}
+		byValue[rule] = append(byValue[rule], mp)
+	}
+
+	values := make([]string, 0, len(byValue))
+	for value := range byValue {
+		values = append(values, value)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		ci, cj := len(distinctMembers(byValue[values[i]])), len(distinctMembers(byValue[values[j]]))
+		if ci != cj {
+			return ci > cj
+		}
+		return values[i] < values[j]
+	})
+
+	return byValue, values
+}
+
+var nonWord = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+func normalizeRule(s string) string {
+	return strings.TrimSpace(nonWord.ReplaceAllString(strings.ToLower(s), " "))
+}
+
+// sharedScope keeps a scope only when every member learned the rule in the
+// same place; otherwise the team rule applies project-wide.
+func sharedScope(group []memberPreference) *signals.Scope {
+	scope := group[0].pref.Scope
+	for _, mp := range group[1:] {
+		if (scope == nil) != (mp.pref.Scope == nil) || scope != nil && scope.Glob() != mp.pref.Scope.Glob() {
+			return nil
+		}
+	}
+	return scope
+}
+
+func mergePreferences(group []memberPreference) signals.Preference {
+	best := group[0].pref
+	for _, mp := range group[1:] {
+		if mp.pref.Confidence > best.Confidence {
+			best = mp.pref
+		}
+	}
+
+	merged := best
+	merged.Evidence = nil
+	merged.Confidence = 0
+	merged.SignalCount = 0
+	merged.SessionCount = 0
+	merged.Projects = nil
+	merged.Members = distinctMembers(group)
+	merged.Scope = sharedScope(group)
+
+	var breakdown signals.ConfidenceBreakdown
+	projects := make(map[string]struct{})
+
+	for _, mp := range group {
+		merged.Confidence += mp.pref.Confidence
+		merged.SignalCount += mp.pref.SignalCount
+		merged.SessionCount += mp.pref.SessionCount
+		breakdown.Recency += mp.pref.Breakdown.Recency
+		breakdown.Frequency += mp.pref.Breakdown.Frequency
+		breakdown.Strength += mp.pref.Breakdown.Strength
+		breakdown.SessionSpread += mp.pref.Breakdown.SessionSpread
+
+		if mp.pref.FirstSeen.Before(merged.FirstSeen) {
+			merged.FirstSeen = mp.pref.FirstSeen
+		}
+		if mp.pref.LastSeen.After(merged.LastSeen) {
+			merged.LastSeen = mp.pref.LastSeen
+		}
+		for _, project := range mp.pref.Projects {
+			projects[filepath.Base(project)] = struct{}{}
+		}
+	}
+
+	n := float64(len(group))
+	merged.Confidence /= n
+	merged.Breakdown = signals.ConfidenceBreakdown{
+		Recency:       breakdown.Recency / n,
+		Frequency:     breakdown.Frequency / n,
+		Strength:      breakdown.Strength / n,
+		SessionSpread: breakdown.SessionSpread / n,
+	}
+	merged.SessionSpan = merged.LastSeen.Sub(merged.FirstSeen)
+
+	for project := range projects {
+		merged.Projects = append(merged.Projects, project)
+	}
+	sort.Strings(merged.Projects)
+
+	return merged
+}
+
+func buildConflict(sample signals.Preference, byValue map[string][]memberPreference, values []string) signals.ConflictingPreference {
+	conflict := signals.ConflictingPreference{
+		Category: sample.Category,
+		Key:      sample.Key,
+	}
+
+	for _, value := range values {
+		group := byValue[value]
+		merged := mergePreferences(group)
+
+		conflict.Values = append(conflict.Values, signals.ConflictValue{
+			Value:       merged.Value,
+			Timestamp:   merged.LastSeen,
+			SignalCount: merged.SignalCount,
+			Strength:    merged.Confidence,
+			Members:     merged.Members,
+		})
+	}
+
+	sort.Slice(conflict.Values, func(i, j int) bool {
+		return conflict.Values[i].Timestamp.After(conflict.Values[j].Timestamp)
+	})
+
+	return conflict
+}
+
+// mergeConflicts adds the members' own conflicts to the team conflicts,
+// joining values that read the same and recording who holds each.
+func mergeConflicts(conflicts []signals.ConflictingPreference, members []Member, agreed map[string]bool) []signals.ConflictingPreference {
+	index := make(map[string]int)
+	for i, c := range conflicts {
+		index[c.Category+"::"+c.Key] = i
+	}
+
+	for _, m := range members {
+		for _, c := range m.Profile.Conflicts {
+			key := c.Category + "::" + c.Key
+			if agreed[key] {
+				continue
+			}
+
+			i, ok := index[key]
+			if !ok {
+				i = len(conflicts)
+				index[key] = i
+				conflicts = append(conflicts, signals.ConflictingPreference{Category: c.Category, Key: c.Key})
+			}
+
+			for _, v := range c.Values {
+				conflicts[i].Values = mergeConflictValue(conflicts[i].Values, v, m.Name)
+			}
+		}
+	}
+
+	for i := range conflicts {
+		values := conflicts[i].Values
+		sort.Slice(values, func(a, b int) bool {
+			return values[a].Timestamp.After(values[b].Timestamp)
+		})
+	}
+
+	return conflicts
+}
+
+func mergeConflictValue(values []signals.ConflictValue, v signals.ConflictValue, member string) []signals.ConflictValue {
+	for i := range values {
+		if normalizeRule(values[i].Value) != normalizeRule(v.Value) {
+			continue
+		}
+		values[i].SignalCount += v.SignalCount
+		values[i].Strength = max(values[i].Strength, v.Strength)
+		if v.Timestamp.After(values[i].Timestamp) {
+			values[i].Timestamp = v.Timestamp
+		}
+		if !slices.Contains(values[i].Members, member) {
+			values[i].Members = append(values[i].Members, member)
+			sort.Strings(values[i].Members)
+		}
+		return values
+	}
+
+	v.Members = []string{member}
+	return append(values, v)
+}
+
+// mergeSuperseded combines the members' records of the same switch from one
+// value to another.
+func mergeSuperseded(members []Member) []signals.SupersededPreference {
+	var merged []signals.SupersededPreference
+	index := make(map[string]int)
+
+	for _, m := range members {
+		for _, s := range m.Profile.Superseded {
+			key := s.Category + "::" + s.Key + "::" + normalizeRule(s.OldValue) + "::" + normalizeRule(s.NewValue)
+			s.Projects = nil
+
+			i, ok := index[key]
+			if !ok {
+				index[key] = len(merged)
+				merged = append(merged, s)
+				continue
+			}
+
+			t := &merged[i]
+			t.OldCount += s.OldCount
+			t.NewCount += s.NewCount
+			if s.SwitchedAt.Before(t.SwitchedAt) {
+				t.SwitchedAt = s.SwitchedAt
+			}
+			if s.OldLastSeen.After(t.OldLastSeen) {
+				t.OldLastSeen = s.OldLastSeen
+			}
+			if s.NewLastSeen.After(t.NewLastSeen) {
+				t.NewLastSeen = s.NewLastSeen
+			}
+		}
+	}
+
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].SwitchedAt.After(merged[j].SwitchedAt)
+	})
+	return merged
+}
+
+func distinctMembers(group []memberPreference) []string {
+	seen := make(map[string]struct{})
+	var names []string
+
+	for _, mp := range group {
+		if _, ok := seen[mp.member]; ok {
+			continue
+		}
+		seen[mp.member] = struct{}{}
+		names = append(names, mp.member)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+func extendRange(r *signals.TimeRange, other signals.TimeRange) {
+	if !other.Start.IsZero() && (r.Start.IsZero() || other.Start.Before(r.Start)) {
+		r.Start = other.Start
+	}
+	if other.End.After(r.End) {
+		r.End = other.End
+	}
+}